@@ -0,0 +1,367 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	v2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+)
+
+// v2FieldsAnnotation keeps the parts of a v2 Syrax that v1 cannot express, so
+// that a v2 object survives a round trip through v1.
+const v2FieldsAnnotation = "targaryen.resource.controller.sigs/v2-fields"
+
+// v2Fields holds the v2 fields without a v1 counterpart.
+type v2Fields struct {
+	Args       []string         `json:"args,omitempty"`
+	Ports      []v2.ServicePort `json:"ports,omitempty"`
+	Components []v2.Component   `json:"components,omitempty"`
+}
+
+// ConvertTo converts this Syrax to the Hub version (v2).
+func (src *Syrax) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v2.Syrax)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec.DeletionPolicy = v2.DeletionPolicy(src.Spec.DeletionPolicy)
+	dst.Spec.Labels = src.Spec.Labels
+	dst.Spec.Paused = src.Spec.Paused
+	dst.Spec.Env = src.Spec.Env
+	if src.Spec.TemplateRef != nil {
+		dst.Spec.TemplateRef = &v2.TemplateReference{Name: src.Spec.TemplateRef.Name}
+	}
+	if src.Spec.RegistryCredentialsRef != nil {
+		dst.Spec.RegistryCredentialsRef = &v2.RegistryCredentialsReference{
+			Namespace: src.Spec.RegistryCredentialsRef.Namespace,
+			Name:      src.Spec.RegistryCredentialsRef.Name,
+		}
+	}
+	if src.Spec.ConfigFiles != nil {
+		dst.Spec.ConfigFiles = &v2.ConfigFilesSpec{
+			MountPath: src.Spec.ConfigFiles.MountPath,
+			Files:     src.Spec.ConfigFiles.Files,
+		}
+	}
+	dst.Spec.RestartAt = src.Spec.RestartAt
+
+	dst.Spec.DeploymentSpec = v2.DeploymentSpec{
+		Name:                    src.Spec.DeploymentSpec.Name,
+		Replicas:                src.Spec.DeploymentSpec.Replicas,
+		Image:                   src.Spec.DeploymentSpec.Image,
+		Command:                 src.Spec.DeploymentSpec.Commands,
+		Strategy:                src.Spec.DeploymentSpec.Strategy,
+		MinReadySeconds:         src.Spec.DeploymentSpec.MinReadySeconds,
+		ProgressDeadlineSeconds: src.Spec.DeploymentSpec.ProgressDeadlineSeconds,
+		Resources:               src.Spec.DeploymentSpec.Resources,
+		LivenessProbe:           src.Spec.DeploymentSpec.LivenessProbe,
+		ReadinessProbe:          src.Spec.DeploymentSpec.ReadinessProbe,
+		ImagePullPolicy:         src.Spec.DeploymentSpec.ImagePullPolicy,
+		ImagePullSecrets:        src.Spec.DeploymentSpec.ImagePullSecrets,
+	}
+	if src.Spec.DeploymentSpec.ImagePolicy != nil {
+		dst.Spec.DeploymentSpec.ImagePolicy = &v2.ImagePolicy{
+			Mode:     v2.ImagePolicyMode(src.Spec.DeploymentSpec.ImagePolicy.Mode),
+			Interval: src.Spec.DeploymentSpec.ImagePolicy.Interval,
+		}
+	}
+
+	dst.Spec.ServiceSpec = v2.ServiceSpec{
+		Name:        src.Spec.ServiceSpec.Name,
+		ServiceType: src.Spec.ServiceSpec.ServiceType,
+	}
+	if src.Spec.ServiceSpec.Port != nil {
+		dst.Spec.ServiceSpec.Ports = []v2.ServicePort{{
+			Port:       *src.Spec.ServiceSpec.Port,
+			TargetPort: src.Spec.ServiceSpec.TargetPort,
+			NodePort:   src.Spec.ServiceSpec.NodePort,
+		}}
+	}
+
+	if src.Spec.Rollback != nil {
+		dst.Spec.Rollback = &v2.RollbackSpec{OnFailure: src.Spec.Rollback.OnFailure}
+	}
+	if src.Spec.Rollout != nil {
+		dst.Spec.Rollout = &v2.RolloutSpec{Mode: v2.RolloutMode(src.Spec.Rollout.Mode)}
+		if src.Spec.Rollout.Canary != nil {
+			dst.Spec.Rollout.Canary = &v2.CanarySpec{}
+			for _, step := range src.Spec.Rollout.Canary.Steps {
+				dst.Spec.Rollout.Canary.Steps = append(dst.Spec.Rollout.Canary.Steps, v2.CanaryStep{
+					Weight: step.Weight,
+					Pause:  step.Pause,
+				})
+			}
+		}
+		if src.Spec.Rollout.BlueGreen != nil {
+			dst.Spec.Rollout.BlueGreen = &v2.BlueGreenSpec{
+				AutoPromoteAfter: src.Spec.Rollout.BlueGreen.AutoPromoteAfter,
+				ScaleDownDelay:   src.Spec.Rollout.BlueGreen.ScaleDownDelay,
+			}
+		}
+	}
+
+	convertStatusTo(&src.Status, &dst.Status)
+
+	return restoreV2Fields(dst)
+}
+
+// ConvertFrom converts from the Hub version (v2) to this version.
+func (dst *Syrax) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v2.Syrax)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec.DeletionPolicy = DeletionPolicy(src.Spec.DeletionPolicy)
+	dst.Spec.Labels = src.Spec.Labels
+	dst.Spec.Paused = src.Spec.Paused
+	dst.Spec.Env = src.Spec.Env
+	if src.Spec.TemplateRef != nil {
+		dst.Spec.TemplateRef = &TemplateReference{Name: src.Spec.TemplateRef.Name}
+	}
+	if src.Spec.RegistryCredentialsRef != nil {
+		dst.Spec.RegistryCredentialsRef = &RegistryCredentialsReference{
+			Namespace: src.Spec.RegistryCredentialsRef.Namespace,
+			Name:      src.Spec.RegistryCredentialsRef.Name,
+		}
+	}
+	if src.Spec.ConfigFiles != nil {
+		dst.Spec.ConfigFiles = &ConfigFilesSpec{
+			MountPath: src.Spec.ConfigFiles.MountPath,
+			Files:     src.Spec.ConfigFiles.Files,
+		}
+	}
+	dst.Spec.RestartAt = src.Spec.RestartAt
+
+	dst.Spec.DeploymentSpec = DeploymentSpec{
+		Name:                    src.Spec.DeploymentSpec.Name,
+		Replicas:                src.Spec.DeploymentSpec.Replicas,
+		Image:                   src.Spec.DeploymentSpec.Image,
+		Commands:                src.Spec.DeploymentSpec.Command,
+		Strategy:                src.Spec.DeploymentSpec.Strategy,
+		MinReadySeconds:         src.Spec.DeploymentSpec.MinReadySeconds,
+		ProgressDeadlineSeconds: src.Spec.DeploymentSpec.ProgressDeadlineSeconds,
+		Resources:               src.Spec.DeploymentSpec.Resources,
+		LivenessProbe:           src.Spec.DeploymentSpec.LivenessProbe,
+		ReadinessProbe:          src.Spec.DeploymentSpec.ReadinessProbe,
+		ImagePullPolicy:         src.Spec.DeploymentSpec.ImagePullPolicy,
+		ImagePullSecrets:        src.Spec.DeploymentSpec.ImagePullSecrets,
+	}
+	if src.Spec.DeploymentSpec.ImagePolicy != nil {
+		dst.Spec.DeploymentSpec.ImagePolicy = &ImagePolicy{
+			Mode:     ImagePolicyMode(src.Spec.DeploymentSpec.ImagePolicy.Mode),
+			Interval: src.Spec.DeploymentSpec.ImagePolicy.Interval,
+		}
+	}
+
+	dst.Spec.ServiceSpec = ServiceSpec{
+		Name:        src.Spec.ServiceSpec.Name,
+		ServiceType: src.Spec.ServiceSpec.ServiceType,
+	}
+	if len(src.Spec.ServiceSpec.Ports) > 0 {
+		port := src.Spec.ServiceSpec.Ports[0]
+		dst.Spec.ServiceSpec.Port = &port.Port
+		dst.Spec.ServiceSpec.TargetPort = port.TargetPort
+		dst.Spec.ServiceSpec.NodePort = port.NodePort
+	}
+
+	if src.Spec.Rollback != nil {
+		dst.Spec.Rollback = &RollbackSpec{OnFailure: src.Spec.Rollback.OnFailure}
+	}
+	if src.Spec.Rollout != nil {
+		dst.Spec.Rollout = &RolloutSpec{Mode: RolloutMode(src.Spec.Rollout.Mode)}
+		if src.Spec.Rollout.Canary != nil {
+			dst.Spec.Rollout.Canary = &CanarySpec{}
+			for _, step := range src.Spec.Rollout.Canary.Steps {
+				dst.Spec.Rollout.Canary.Steps = append(dst.Spec.Rollout.Canary.Steps, CanaryStep{
+					Weight: step.Weight,
+					Pause:  step.Pause,
+				})
+			}
+		}
+		if src.Spec.Rollout.BlueGreen != nil {
+			dst.Spec.Rollout.BlueGreen = &BlueGreenSpec{
+				AutoPromoteAfter: src.Spec.Rollout.BlueGreen.AutoPromoteAfter,
+				ScaleDownDelay:   src.Spec.Rollout.BlueGreen.ScaleDownDelay,
+			}
+		}
+	}
+
+	convertStatusFrom(&src.Status, &dst.Status)
+
+	return saveV2Fields(src, dst)
+}
+
+func convertStatusTo(src *SyraxStatus, dst *v2.SyraxStatus) {
+	dst.AvailableReplicas = src.AvailableReplicas
+	dst.UpdatedReplicas = src.UpdatedReplicas
+	dst.ReadyReplicas = src.ReadyReplicas
+	dst.CurrentImage = src.CurrentImage
+	dst.TargetImage = src.TargetImage
+	dst.ServiceName = src.ServiceName
+	dst.ServiceType = src.ServiceType
+	dst.RolledBackGeneration = src.RolledBackGeneration
+	dst.Conditions = src.Conditions
+	if src.Canary != nil {
+		dst.Canary = &v2.CanaryStatus{
+			Phase:              v2.CanaryPhase(src.Canary.Phase),
+			ObservedGeneration: src.Canary.ObservedGeneration,
+			Image:              src.Canary.Image,
+			CurrentStep:        src.Canary.CurrentStep,
+			StepStartedAt:      src.Canary.StepStartedAt,
+			Replicas:           src.Canary.Replicas,
+			AvailableReplicas:  src.Canary.AvailableReplicas,
+		}
+	}
+	if src.BlueGreen != nil {
+		dst.BlueGreen = &v2.BlueGreenStatus{
+			ActiveColor:       src.BlueGreen.ActiveColor,
+			PreviewImage:      src.BlueGreen.PreviewImage,
+			PreviewReadySince: src.BlueGreen.PreviewReadySince,
+			ScaleDownAt:       src.BlueGreen.ScaleDownAt,
+		}
+	}
+	for _, migration := range src.Migrations {
+		dst.Migrations = append(dst.Migrations, v2.NameMigration{
+			Kind:        migration.Kind,
+			From:        migration.From,
+			To:          migration.To,
+			Phase:       v2.MigrationPhase(migration.Phase),
+			StartedAt:   migration.StartedAt,
+			CompletedAt: migration.CompletedAt,
+		})
+	}
+	for _, component := range src.Components {
+		dst.Components = append(dst.Components, v2.ComponentStatus(component))
+	}
+	if src.Template != nil {
+		dst.Template = &v2.ResolvedTemplate{Name: src.Template.Name, Generation: src.Template.Generation, Labels: src.Template.Labels}
+	}
+	for _, image := range src.ResolvedImages {
+		dst.ResolvedImages = append(dst.ResolvedImages, v2.ResolvedImage(image))
+	}
+	dst.LastRestartAt = src.LastRestartAt
+}
+
+func convertStatusFrom(src *v2.SyraxStatus, dst *SyraxStatus) {
+	dst.AvailableReplicas = src.AvailableReplicas
+	dst.UpdatedReplicas = src.UpdatedReplicas
+	dst.ReadyReplicas = src.ReadyReplicas
+	dst.CurrentImage = src.CurrentImage
+	dst.TargetImage = src.TargetImage
+	dst.ServiceName = src.ServiceName
+	dst.ServiceType = src.ServiceType
+	dst.RolledBackGeneration = src.RolledBackGeneration
+	dst.Conditions = src.Conditions
+	if src.Canary != nil {
+		dst.Canary = &CanaryStatus{
+			Phase:              CanaryPhase(src.Canary.Phase),
+			ObservedGeneration: src.Canary.ObservedGeneration,
+			Image:              src.Canary.Image,
+			CurrentStep:        src.Canary.CurrentStep,
+			StepStartedAt:      src.Canary.StepStartedAt,
+			Replicas:           src.Canary.Replicas,
+			AvailableReplicas:  src.Canary.AvailableReplicas,
+		}
+	}
+	if src.BlueGreen != nil {
+		dst.BlueGreen = &BlueGreenStatus{
+			ActiveColor:       src.BlueGreen.ActiveColor,
+			PreviewImage:      src.BlueGreen.PreviewImage,
+			PreviewReadySince: src.BlueGreen.PreviewReadySince,
+			ScaleDownAt:       src.BlueGreen.ScaleDownAt,
+		}
+	}
+	for _, migration := range src.Migrations {
+		dst.Migrations = append(dst.Migrations, NameMigration{
+			Kind:        migration.Kind,
+			From:        migration.From,
+			To:          migration.To,
+			Phase:       MigrationPhase(migration.Phase),
+			StartedAt:   migration.StartedAt,
+			CompletedAt: migration.CompletedAt,
+		})
+	}
+	for _, component := range src.Components {
+		dst.Components = append(dst.Components, ComponentStatus(component))
+	}
+	if src.Template != nil {
+		dst.Template = &ResolvedTemplate{Name: src.Template.Name, Generation: src.Template.Generation, Labels: src.Template.Labels}
+	}
+	for _, image := range src.ResolvedImages {
+		dst.ResolvedImages = append(dst.ResolvedImages, ResolvedImage(image))
+	}
+	dst.LastRestartAt = src.LastRestartAt
+}
+
+// saveV2Fields records the v2 fields that were dropped while converting src
+// into dst in an annotation of dst.
+func saveV2Fields(src *v2.Syrax, dst *Syrax) error {
+	fields := v2Fields{Args: src.Spec.DeploymentSpec.Args, Components: src.Spec.Components}
+	ports := src.Spec.ServiceSpec.Ports
+	if len(ports) > 1 || (len(ports) == 1 && ports[0].Name != "") {
+		fields.Ports = ports
+	}
+	if len(fields.Args) == 0 && len(fields.Ports) == 0 && len(fields.Components) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	annotations := make(map[string]string, len(dst.Annotations)+1)
+	for k, v := range dst.Annotations {
+		annotations[k] = v
+	}
+	annotations[v2FieldsAnnotation] = string(raw)
+	dst.Annotations = annotations
+	return nil
+}
+
+// restoreV2Fields brings back the v2 fields saved by saveV2Fields. The first
+// port is taken from the v1 fields, which may have been changed since.
+func restoreV2Fields(dst *v2.Syrax) error {
+	raw, ok := dst.Annotations[v2FieldsAnnotation]
+	if !ok {
+		return nil
+	}
+	fields := v2Fields{}
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		return err
+	}
+
+	annotations := make(map[string]string, len(dst.Annotations))
+	for k, v := range dst.Annotations {
+		if k != v2FieldsAnnotation {
+			annotations[k] = v
+		}
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	dst.Annotations = annotations
+
+	dst.Spec.DeploymentSpec.Args = fields.Args
+	dst.Spec.Components = fields.Components
+	if len(fields.Ports) > 0 && len(dst.Spec.ServiceSpec.Ports) > 0 {
+		first := dst.Spec.ServiceSpec.Ports[0]
+		first.Name = fields.Ports[0].Name
+		dst.Spec.ServiceSpec.Ports = append([]v2.ServicePort{first}, fields.Ports[1:]...)
+	}
+	return nil
+}
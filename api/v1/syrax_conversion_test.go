@@ -0,0 +1,136 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	fuzz "github.com/google/gofuzz"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+)
+
+const fuzzIterations = 1000
+
+func newFuzzer() *fuzz.Fuzzer {
+	return fuzz.New().NilChance(0.3).NumElements(0, 3).Funcs(
+		// a v1 service only has ports when the port itself is set.
+		func(spec *ServiceSpec, c fuzz.Continue) {
+			c.FuzzNoCustom(spec)
+			if spec.Port == nil {
+				spec.TargetPort = nil
+				spec.NodePort = nil
+			}
+		},
+	)
+}
+
+func TestSyraxV1RoundTrip(t *testing.T) {
+	f := newFuzzer()
+	for i := 0; i < fuzzIterations; i++ {
+		original := &Syrax{}
+		f.Fuzz(original)
+		original.TypeMeta = metav1.TypeMeta{}
+
+		hub := &v2.Syrax{}
+		if err := original.DeepCopy().ConvertTo(hub); err != nil {
+			t.Fatalf("converting to v2: %v", err)
+		}
+		roundTripped := &Syrax{}
+		if err := roundTripped.ConvertFrom(hub); err != nil {
+			t.Fatalf("converting from v2: %v", err)
+		}
+
+		if !equality.Semantic.DeepEqual(original, roundTripped) {
+			t.Fatalf("v1 -> v2 -> v1 changed the object (-want +got):\n%s", cmp.Diff(original, roundTripped))
+		}
+	}
+}
+
+func TestSyraxV2RoundTrip(t *testing.T) {
+	f := newFuzzer()
+	for i := 0; i < fuzzIterations; i++ {
+		original := &v2.Syrax{}
+		f.Fuzz(original)
+		original.TypeMeta = metav1.TypeMeta{}
+
+		spoke := &Syrax{}
+		if err := spoke.ConvertFrom(original.DeepCopy()); err != nil {
+			t.Fatalf("converting from v2: %v", err)
+		}
+		roundTripped := &v2.Syrax{}
+		if err := spoke.ConvertTo(roundTripped); err != nil {
+			t.Fatalf("converting to v2: %v", err)
+		}
+
+		if !equality.Semantic.DeepEqual(original, roundTripped) {
+			t.Fatalf("v2 -> v1 -> v2 changed the object (-want +got):\n%s", cmp.Diff(original, roundTripped))
+		}
+	}
+}
+
+func TestSyraxV2FieldsAnnotation(t *testing.T) {
+	hub := &v2.Syrax{
+		Spec: v2.SyraxSpec{
+			DeploymentSpec: v2.DeploymentSpec{
+				Image:   "nginx",
+				Command: []string{"nginx"},
+				Args:    []string{"-g", "daemon off;"},
+			},
+			ServiceSpec: v2.ServiceSpec{
+				Ports: []v2.ServicePort{
+					{Name: "http", Port: 80},
+					{Name: "metrics", Port: 9090},
+				},
+			},
+		},
+	}
+
+	spoke := &Syrax{}
+	if err := spoke.ConvertFrom(hub); err != nil {
+		t.Fatalf("converting from v2: %v", err)
+	}
+	if got := spoke.Spec.DeploymentSpec.Commands; !equality.Semantic.DeepEqual(got, []string{"nginx"}) {
+		t.Errorf("commands = %v, want [nginx]", got)
+	}
+	if spoke.Spec.ServiceSpec.Port == nil || *spoke.Spec.ServiceSpec.Port != 80 {
+		t.Errorf("port = %v, want 80", spoke.Spec.ServiceSpec.Port)
+	}
+	if _, ok := spoke.Annotations[v2FieldsAnnotation]; !ok {
+		t.Errorf("expected the %s annotation to keep the v2-only fields", v2FieldsAnnotation)
+	}
+
+	port := int32(8080)
+	spoke.Spec.ServiceSpec.Port = &port
+	converted := &v2.Syrax{}
+	if err := spoke.ConvertTo(converted); err != nil {
+		t.Fatalf("converting to v2: %v", err)
+	}
+	want := []v2.ServicePort{
+		{Name: "http", Port: 8080},
+		{Name: "metrics", Port: 9090},
+	}
+	if !equality.Semantic.DeepEqual(converted.Spec.ServiceSpec.Ports, want) {
+		t.Errorf("ports (-want +got):\n%s", cmp.Diff(want, converted.Spec.ServiceSpec.Ports))
+	}
+	if _, ok := converted.Annotations[v2FieldsAnnotation]; ok {
+		t.Errorf("the %s annotation should not be kept on v2 objects", v2FieldsAnnotation)
+	}
+}
@@ -0,0 +1,543 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SyraxSpec defines the desired state of Syrax
+type SyraxSpec struct {
+	// DeletionPolicy decides what happens to the children when the Syrax is
+	// deleted: Delete keeps them, WipeOut lets them be garbage collected.
+	// Defaults to WipeOut. It cannot leave Delete while the Syrax is being
+	// deleted, which the validating webhook enforces.
+	// +kubebuilder:validation:Enum=Delete;WipeOut
+	// +optional
+	DeletionPolicy DeletionPolicy    `json:"deletionPolicy,omitempty"`
+	DeploymentSpec DeploymentSpec    `json:"deploymentSpec"`
+	ServiceSpec    ServiceSpec       `json:"serviceSpec,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	// Rollback configures how failed rollouts are handled.
+	// +optional
+	Rollback *RollbackSpec `json:"rollback,omitempty"`
+	// Rollout configures how changes to the pod template are rolled out.
+	// +optional
+	Rollout *RolloutSpec `json:"rollout,omitempty"`
+	// Paused stops the controller from creating, updating or deleting the
+	// children of the Syrax. Status is still reported while paused.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+	// Env is set in the container of the deployment and of every component.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	// Components are further workloads of the Syrax, such as a worker or a
+	// scheduler, each reconciled into its own deployment and optional
+	// service. They share the labels, env and deletion policy of the Syrax.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=20
+	Components []Component `json:"components,omitempty"`
+	// TemplateRef names the SyraxTemplate whose defaults the spec is merged
+	// over. Fields set in the spec win, maps and env are merged by key.
+	// +optional
+	TemplateRef *TemplateReference `json:"templateRef,omitempty"`
+	// RegistryCredentialsRef names a docker-config Secret of another
+	// namespace. The controller copies it next to the Syrax, keeps the copy
+	// in sync and pulls the images of every deployment with it.
+	// +optional
+	RegistryCredentialsRef *RegistryCredentialsReference `json:"registryCredentialsRef,omitempty"`
+	// ConfigFiles are written to a ConfigMap owned by the Syrax and mounted in
+	// the container of every deployment. Changing them restarts the pods.
+	// +optional
+	ConfigFiles *ConfigFilesSpec `json:"configFiles,omitempty"`
+	// RestartAt restarts the pods of every deployment with a rolling update
+	// whenever it changes, for instance when set to the current time.
+	// +optional
+	RestartAt *metav1.Time `json:"restartAt,omitempty"`
+}
+
+// ConfigFilesSpec holds small configuration files shipped with the Syrax.
+type ConfigFilesSpec struct {
+	// MountPath is the directory the files are mounted at. Defaults to
+	// /etc/config.
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+	// Files maps the names of the files to their content. Names are keys of
+	// a ConfigMap, and may also be read as key/value data.
+	// +kubebuilder:validation:MinProperties=1
+	// +kubebuilder:validation:XValidation:rule="self.all(name, name.matches('^[-._a-zA-Z0-9]+$'))",message="file names may only contain letters, digits, '-', '_' and '.'"
+	Files map[string]string `json:"files"`
+}
+
+// RegistryCredentialsReference refers to a Secret of type
+// kubernetes.io/dockerconfigjson, usually kept in a central namespace.
+type RegistryCredentialsReference struct {
+	// Namespace of the Secret.
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+	// Name of the Secret.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// TemplateReference refers to a cluster-scoped SyraxTemplate.
+type TemplateReference struct {
+	// Name of the SyraxTemplate.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// Component is a workload of the Syrax next to its main deployment.
+type Component struct {
+	// Name of the component. Its children are named after the Syrax and the
+	// component, the names in deploymentSpec and serviceSpec are not used.
+	// +kubebuilder:validation:Pattern=`^[a-z]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=40
+	Name string `json:"name"`
+	// DeploymentSpec describes the deployment of the component.
+	DeploymentSpec DeploymentSpec `json:"deploymentSpec"`
+	// ServiceSpec exposes the component. No service is created without it.
+	// +optional
+	ServiceSpec *ServiceSpec `json:"serviceSpec,omitempty"`
+}
+
+const (
+	DeletionPolicyDelete  DeletionPolicy = "Delete"
+	DeletionPolicyWipeOut DeletionPolicy = "WipeOut"
+)
+
+type DeletionPolicy string
+
+type DeploymentSpec struct {
+	// Name is appended to the name of the Syrax to name the deployment.
+	// Changing it renames the deployment without downtime, see
+	// status.migrations.
+	// +optional
+	Name     string `json:"name,omitempty"`
+	Replicas *int32 `json:"replicas,omitempty"`
+	Image    string `json:"image"`
+	// Command overrides the entrypoint of the container image.
+	// +optional
+	Command []string `json:"command,omitempty"`
+	// Args are the arguments passed to the entrypoint.
+	// +optional
+	Args []string `json:"args,omitempty"`
+	// Strategy is the deployment strategy used to replace old pods with new ones.
+	// Defaults to RollingUpdate when not set.
+	// +optional
+	Strategy *appsv1.DeploymentStrategy `json:"strategy,omitempty"`
+	// MinReadySeconds is the minimum number of seconds a new pod should be ready
+	// before it is considered available.
+	// +optional
+	MinReadySeconds int32 `json:"minReadySeconds,omitempty"`
+	// ProgressDeadlineSeconds is the maximum time in seconds for a rollout to
+	// make progress before it is reported as failed.
+	// +optional
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+	// Resources are the compute resources of the container.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// LivenessProbe restarts the container when it fails.
+	// +optional
+	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty" patchStrategy:"replace"`
+	// ReadinessProbe takes the pod out of the service endpoints when it fails.
+	// +optional
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty" patchStrategy:"replace"`
+	// ImagePullPolicy tells when the kubelet pulls the image. Defaults to
+	// Always for the latest tag and IfNotPresent otherwise.
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
+	// +optional
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// ImagePullSecrets name the Secrets of the namespace the image is pulled
+	// with, next to the copy of registryCredentialsRef.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// ImagePolicy runs the image by digest rather than by tag, so that every
+	// replica runs the same build. See status.resolvedImages.
+	// +optional
+	ImagePolicy *ImagePolicy `json:"imagePolicy,omitempty"`
+}
+
+const (
+	// ImagePolicyPin resolves the tag of the image to a digest once, and
+	// again only when the image of the spec changes.
+	ImagePolicyPin ImagePolicyMode = "Pin"
+	// ImagePolicyTrack resolves the tag again every interval and rolls out
+	// the new digest when the tag moved.
+	ImagePolicyTrack ImagePolicyMode = "Track"
+)
+
+type ImagePolicyMode string
+
+// ImagePolicy tells how the tag of the image is resolved to a digest.
+type ImagePolicy struct {
+	// Mode is Pin or Track.
+	// +kubebuilder:validation:Enum=Pin;Track
+	Mode ImagePolicyMode `json:"mode"`
+	// Interval between two resolutions of the tag in Track mode. Defaults to
+	// five minutes.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// RollbackSpec configures automatic rollback of failed rollouts.
+type RollbackSpec struct {
+	// OnFailure restores the last known-good pod template when a rollout
+	// exceeds its progress deadline. The failing spec is not re-applied
+	// until the Syrax spec changes again.
+	// +optional
+	OnFailure bool `json:"onFailure,omitempty"`
+}
+
+const (
+	// RolloutModeStandard lets the deployment roll out a new pod template with its own strategy.
+	RolloutModeStandard RolloutMode = "Standard"
+	// RolloutModeCanary shifts replicas to a canary deployment step by step before promoting it.
+	RolloutModeCanary RolloutMode = "Canary"
+	// RolloutModeBlueGreen brings up the new pod template next to the old one
+	// and switches the service over once it is approved.
+	RolloutModeBlueGreen RolloutMode = "BlueGreen"
+)
+
+type RolloutMode string
+
+// RolloutSpec configures how a new pod template reaches the pods.
+type RolloutSpec struct {
+	// Mode selects the rollout mode. Defaults to Standard.
+	// +kubebuilder:validation:Enum=Standard;Canary;BlueGreen
+	// +optional
+	Mode RolloutMode `json:"mode,omitempty"`
+	// Canary configures the Canary rollout mode.
+	// +optional
+	Canary *CanarySpec `json:"canary,omitempty"`
+	// BlueGreen configures the BlueGreen rollout mode.
+	// +optional
+	BlueGreen *BlueGreenSpec `json:"blueGreen,omitempty"`
+}
+
+// CanarySpec describes the steps a canary rollout advances through.
+type CanarySpec struct {
+	// Steps are applied in order. The canary is promoted after the last step.
+	// +kubebuilder:validation:MinItems=1
+	Steps []CanaryStep `json:"steps"`
+}
+
+// CanaryStep sets the share of replicas running the new pod template.
+type CanaryStep struct {
+	// Weight is the percentage of the desired replicas that run the canary.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight int32 `json:"weight"`
+	// Pause is how long to stay at this step once the canary pods are ready.
+	// +optional
+	Pause *metav1.Duration `json:"pause,omitempty"`
+}
+
+// BlueGreenSpec configures how a blue-green rollout is promoted.
+type BlueGreenSpec struct {
+	// AutoPromoteAfter promotes the new color once its pods have been ready
+	// for this long. Without it, promotion waits for the promote annotation.
+	// +optional
+	AutoPromoteAfter *metav1.Duration `json:"autoPromoteAfter,omitempty"`
+	// ScaleDownDelay is how long the previous color keeps its pods after a
+	// promotion, so that clients can drain from it. Defaults to 30s.
+	// +optional
+	ScaleDownDelay *metav1.Duration `json:"scaleDownDelay,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:rule="!has(self.type) || self.type in ['NodePort', 'LoadBalancer'] || !has(self.ports) || self.ports.all(p, !has(p.nodePort))",message="nodePort can only be set for NodePort and LoadBalancer services"
+type ServiceSpec struct {
+	// Name is appended to the name of the Syrax to name the service.
+	// Changing it renames the service, see status.migrations.
+	// +optional
+	Name        string             `json:"name,omitempty"`
+	ServiceType corev1.ServiceType `json:"type,omitempty"`
+	// Ports exposed by the service. Ports need a name when there is more than one.
+	// +optional
+	// +listType=atomic
+	// +kubebuilder:validation:MaxItems=100
+	Ports []ServicePort `json:"ports,omitempty"`
+}
+
+// ServicePort describes a port exposed by the service and the container port
+// it forwards to.
+type ServicePort struct {
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Port is the port exposed by the service.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+	// TargetPort is the container port traffic is forwarded to. Defaults to Port.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	TargetPort *int32 `json:"targetPort,omitempty"`
+	// NodePort is the port allocated on every node for NodePort and LoadBalancer services.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	NodePort *int32 `json:"nodePort,omitempty"`
+}
+
+// SyraxStatus defines the observed state of Syrax
+type SyraxStatus struct {
+	// AvailableReplicas is the number of available pods serving traffic.
+	AvailableReplicas *int32 `json:"availableReplicas"`
+	// UpdatedReplicas is the number of pods running the target pod template.
+	// +optional
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+	// ReadyReplicas is the number of pods that are ready.
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	// CurrentImage is the image of the last completed rollout.
+	// +optional
+	CurrentImage string `json:"currentImage,omitempty"`
+	// TargetImage is the image the deployment is rolling out to.
+	// +optional
+	TargetImage string `json:"targetImage,omitempty"`
+	// ServiceName is the name of the main service, which carries a generated
+	// suffix.
+	// +optional
+	ServiceName string `json:"serviceName,omitempty"`
+	// ServiceType is the type of the main service.
+	// +optional
+	ServiceType corev1.ServiceType `json:"serviceType,omitempty"`
+	// RolledBackGeneration is the generation of the spec whose rollout failed
+	// and was rolled back.
+	// +optional
+	RolledBackGeneration int64 `json:"rolledBackGeneration,omitempty"`
+	// Canary reports the progress of the last canary rollout.
+	// +optional
+	Canary *CanaryStatus `json:"canary,omitempty"`
+	// BlueGreen reports which color serves traffic and the state of the preview.
+	// +optional
+	BlueGreen *BlueGreenStatus `json:"blueGreen,omitempty"`
+	// Migrations records the renames of the deployment and the service that
+	// followed a change of their name in the spec.
+	// +optional
+	// +listType=map
+	// +listMapKey=kind
+	Migrations []NameMigration `json:"migrations,omitempty"`
+	// Components reports the children of every component.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Components []ComponentStatus `json:"components,omitempty"`
+	// Template reports the SyraxTemplate the spec was last resolved with.
+	// +optional
+	Template *ResolvedTemplate `json:"template,omitempty"`
+	// ResolvedImages are the digests the images with an image policy were
+	// resolved to, by image.
+	// +optional
+	// +listType=map
+	// +listMapKey=image
+	ResolvedImages []ResolvedImage `json:"resolvedImages,omitempty"`
+	// LastRestartAt is the restartAt of the spec the pods were last
+	// restarted for.
+	// +optional
+	LastRestartAt *metav1.Time `json:"lastRestartAt,omitempty"`
+	// Conditions represent the latest available observations of the Syrax state.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// ConditionProgressing mirrors the Progressing condition of the child deployment.
+	ConditionProgressing = "Progressing"
+	// ConditionRolledBack is true when a failed rollout was rolled back to the
+	// last known-good pod template.
+	ConditionRolledBack = "RolledBack"
+	// ConditionPaused is true while the controller leaves the children of the
+	// Syrax untouched.
+	ConditionPaused = "Paused"
+	// ConditionDegraded is true when the children of the Syrax cannot be
+	// reconciled because of an error that retrying does not fix.
+	ConditionDegraded = "Degraded"
+	// ConditionReady is true when every replica of the Syrax is available and
+	// its service is reachable.
+	ConditionReady = "Ready"
+	// ConditionPolicyViolation is true when the Syrax violates a SyraxPolicy
+	// of its namespace, such as one created after the Syrax.
+	ConditionPolicyViolation = "PolicyViolation"
+)
+
+const (
+	CanaryPhaseProgressing CanaryPhase = "Progressing"
+	CanaryPhasePromoted    CanaryPhase = "Promoted"
+	CanaryPhaseAborted     CanaryPhase = "Aborted"
+)
+
+type CanaryPhase string
+
+// CanaryStatus defines the observed state of a canary rollout.
+type CanaryStatus struct {
+	// Phase of the canary rollout.
+	Phase CanaryPhase `json:"phase"`
+	// ObservedGeneration is the Syrax generation being rolled out.
+	ObservedGeneration int64 `json:"observedGeneration"`
+	// Image is the image running in the canary deployment.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// CurrentStep is the index of the step the canary is at.
+	CurrentStep int32 `json:"currentStep"`
+	// StepStartedAt is when the canary pods of the current step became ready.
+	// +optional
+	StepStartedAt *metav1.Time `json:"stepStartedAt,omitempty"`
+	// Replicas is the number of replicas requested for the canary deployment.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+	// AvailableReplicas is the number of available canary pods.
+	// +optional
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+}
+
+// BlueGreenStatus defines the observed state of a blue-green rollout.
+type BlueGreenStatus struct {
+	// ActiveColor is the color the service sends traffic to.
+	ActiveColor string `json:"activeColor"`
+	// PreviewImage is the image running behind the preview service.
+	// +optional
+	PreviewImage string `json:"previewImage,omitempty"`
+	// PreviewReadySince is when all pods of the preview became available.
+	// +optional
+	PreviewReadySince *metav1.Time `json:"previewReadySince,omitempty"`
+	// ScaleDownAt is when the previous color is scaled down after a promotion.
+	// +optional
+	ScaleDownAt *metav1.Time `json:"scaleDownAt,omitempty"`
+}
+
+// MigrationPhase is the phase of the rename of a child.
+type MigrationPhase string
+
+const (
+	// MigrationPhasePending waits for a canary or blue-green rollout mode to be
+	// turned off before renaming the child.
+	MigrationPhasePending MigrationPhase = "Pending"
+	// MigrationPhaseProgressing waits for the child under the new name to be ready.
+	MigrationPhaseProgressing MigrationPhase = "Progressing"
+	// MigrationPhaseCompleted means the child under the new name took over and
+	// the old one was deleted.
+	MigrationPhaseCompleted MigrationPhase = "Completed"
+	// MigrationPhaseAborted means the name was reverted or the Syrax deleted
+	// before the child under the new name took over.
+	MigrationPhaseAborted MigrationPhase = "Aborted"
+)
+
+// NameMigration reports the rename of a child of the Syrax.
+type NameMigration struct {
+	// Kind of the renamed child, Deployment or Service.
+	Kind string `json:"kind"`
+	// From is the name of the child being replaced.
+	From string `json:"from"`
+	// To is the name of the child replacing it.
+	// +optional
+	To string `json:"to,omitempty"`
+	// Phase of the migration.
+	Phase MigrationPhase `json:"phase"`
+	// StartedAt is when the child under the new name was created.
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+	// CompletedAt is when the old child was deleted.
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+}
+
+// ComponentStatus reports the children of a component.
+type ComponentStatus struct {
+	// Name of the component.
+	Name string `json:"name"`
+	// DeploymentName is the name of the deployment of the component.
+	// +optional
+	DeploymentName string `json:"deploymentName,omitempty"`
+	// ServiceName is the name of the service of the component, if any.
+	// +optional
+	ServiceName string `json:"serviceName,omitempty"`
+	// AvailableReplicas is the number of available pods of the component.
+	// +optional
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+	// UpdatedReplicas is the number of pods running the latest pod template.
+	// +optional
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+	// Ready is true when every replica is available and the service is reachable.
+	Ready bool `json:"ready"`
+	// Message tells why the component is not ready.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ResolvedImage is the digest a tag pointed to when it was last resolved.
+type ResolvedImage struct {
+	// Image is the image of the spec, with its tag.
+	Image string `json:"image"`
+	// Digest of the manifest the tag pointed to, such as sha256:...
+	Digest string `json:"digest"`
+	// ResolvedAt is the last time the tag was resolved.
+	ResolvedAt metav1.Time `json:"resolvedAt"`
+}
+
+// ResolvedTemplate identifies the version of a SyraxTemplate.
+type ResolvedTemplate struct {
+	// Name of the SyraxTemplate.
+	Name string `json:"name"`
+	// Generation of the SyraxTemplate the spec was resolved with.
+	Generation int64 `json:"generation"`
+	// Labels of the SyraxTemplate. They are put on the children and their
+	// pods, but kept out of the selectors, which cannot be changed.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:storageversion
+//+kubebuilder:resource:shortName=srx,categories=all
+//+kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.deploymentSpec.image`
+//+kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.spec.deploymentSpec.replicas`
+//+kubebuilder:printcolumn:name="Available",type=integer,JSONPath=`.status.availableReplicas`
+//+kubebuilder:printcolumn:name="Service Type",type=string,JSONPath=`.status.serviceType`
+//+kubebuilder:printcolumn:name="Service",type=string,JSONPath=`.status.serviceName`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Syrax is the Schema for the syraxes API
+type Syrax struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SyraxSpec   `json:"spec,omitempty"`
+	Status SyraxStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SyraxList contains a list of Syrax
+type SyraxList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Syrax `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Syrax{}, &SyraxList{})
+}
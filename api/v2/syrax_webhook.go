@@ -0,0 +1,133 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var syraxlog = logf.Log.WithName("syrax-resource")
+
+// SetupWebhookWithManager registers the webhooks of Syrax with the manager.
+// Since Syrax v2 is the conversion hub, this also serves /convert for every
+// version of Syrax known to the manager's scheme.
+func (r *Syrax) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	syraxlog.Info("setting up webhooks")
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&SyraxValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-targaryen-resource-controller-sigs-v2-syrax,mutating=false,failurePolicy=fail,sideEffects=None,groups=targaryen.resource.controller.sigs,resources=syraxes,verbs=create;update,versions=v2,name=vsyrax.kb.io,admissionReviewVersions=v1
+
+//+kubebuilder:object:generate=false
+
+// SyraxValidator rejects the Syraxes that violate a SyraxPolicy of their
+// namespace, merged over their template, and the deletion policy changes of
+// Syraxes being deleted.
+type SyraxValidator struct {
+	Client client.Reader
+}
+
+var _ admission.CustomValidator = &SyraxValidator{}
+
+// ValidateCreate checks a new Syrax against the policies of its namespace.
+func (v *SyraxValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	syrax, ok := obj.(*Syrax)
+	if !ok {
+		return nil, fmt.Errorf("expected a Syrax but got a %T", obj)
+	}
+	return nil, v.validate(ctx, syrax)
+}
+
+// ValidateUpdate checks a changed spec against the policies of the namespace.
+// Updates that leave the spec alone, such as the metadata and status writes
+// of the controller, are let through so that Syraxes created before a policy
+// can still be reconciled and deleted. A Syrax being deleted cannot leave the
+// Delete policy, as its finalizer is already cleaning up the children.
+func (v *SyraxValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldSyrax, ok := oldObj.(*Syrax)
+	if !ok {
+		return nil, fmt.Errorf("expected a Syrax but got a %T", oldObj)
+	}
+	syrax, ok := newObj.(*Syrax)
+	if !ok {
+		return nil, fmt.Errorf("expected a Syrax but got a %T", newObj)
+	}
+	if oldSyrax.DeletionTimestamp != nil && oldSyrax.Spec.DeletionPolicy == DeletionPolicyDelete &&
+		syrax.Spec.DeletionPolicy != DeletionPolicyDelete {
+		return nil, apierrors.NewInvalid(GroupVersion.WithKind("Syrax").GroupKind(), syrax.Name, field.ErrorList{
+			field.Forbidden(field.NewPath("spec", "deletionPolicy"), "cannot be changed from Delete while the Syrax is being deleted"),
+		})
+	}
+	if syrax.DeletionTimestamp != nil || equality.Semantic.DeepEqual(oldSyrax.Spec, syrax.Spec) {
+		return nil, nil
+	}
+	return nil, v.validate(ctx, syrax)
+}
+
+// ValidateDelete lets every Syrax be deleted.
+func (v *SyraxValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the Syrax against the policies of its namespace once merged
+// over its template, as the controller does. A Syrax whose template does not
+// exist yet is checked as it is, and reports the violations of the template
+// in its PolicyViolation condition once the template is created.
+func (v *SyraxValidator) validate(ctx context.Context, syrax *Syrax) error {
+	policies := &SyraxPolicyList{}
+	if err := v.Client.List(ctx, policies, client.InNamespace(syrax.Namespace)); err != nil {
+		return apierrors.NewInternalError(fmt.Errorf("listing the syraxpolicies: %w", err))
+	}
+	if len(policies.Items) == 0 {
+		return nil
+	}
+	if syrax.Spec.TemplateRef != nil {
+		template := &SyraxTemplate{}
+		err := v.Client.Get(ctx, client.ObjectKey{Name: syrax.Spec.TemplateRef.Name}, template)
+		switch {
+		case err == nil:
+			syrax = syrax.DeepCopy()
+			if err := template.Apply(syrax); err != nil {
+				return apierrors.NewInternalError(err)
+			}
+		case !apierrors.IsNotFound(err):
+			return apierrors.NewInternalError(fmt.Errorf("getting the syraxtemplate: %w", err))
+		}
+	}
+	var errs field.ErrorList
+	for i := range policies.Items {
+		errs = append(errs, policies.Items[i].Violations(syrax)...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Syrax").GroupKind(), syrax.Name, errs)
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Syrax Webhook", func() {
+	const resourceName = "webhook-resource"
+	// testFinalizer stands in for the finalizer of the controller, which does
+	// not run in this suite.
+	const testFinalizer = "targaryen.resource.controller.sigs/test"
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+
+	newSyrax := func() *Syrax {
+		return &Syrax{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      resourceName,
+				Namespace: "default",
+			},
+			Spec: SyraxSpec{
+				DeploymentSpec: DeploymentSpec{Image: "nginx:1.25"},
+				ServiceSpec:    ServiceSpec{Ports: []ServicePort{{Port: 80}}},
+			},
+		}
+	}
+
+	expectInvalid := func(err error, message string) {
+		ExpectWithOffset(1, err).To(HaveOccurred())
+		ExpectWithOffset(1, apierrors.IsInvalid(err)).To(BeTrue(), err.Error())
+		ExpectWithOffset(1, err.Error()).To(ContainSubstring(message))
+	}
+
+	AfterEach(func() {
+		syrax := &Syrax{}
+		err := k8sClient.Get(ctx, typeNamespacedName, syrax)
+		if apierrors.IsNotFound(err) {
+			return
+		}
+		Expect(err).NotTo(HaveOccurred())
+		if len(syrax.Finalizers) > 0 {
+			syrax.Finalizers = nil
+			Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, syrax))).To(Succeed())
+	})
+
+	Context("When a SyraxPolicy applies to the namespace", func() {
+		const policyName = "restricted"
+
+		compliantSyrax := func() *Syrax {
+			syrax := newSyrax()
+			syrax.Spec.Labels = map[string]string{"team": "payments"}
+			syrax.Spec.DeploymentSpec.Image = "registry.example.com/shop/api:1.0"
+			syrax.Spec.DeploymentSpec.Replicas = ptr.To[int32](2)
+			syrax.Spec.DeploymentSpec.Resources = &corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+			}
+			syrax.Spec.ServiceSpec.ServiceType = corev1.ServiceTypeClusterIP
+			return syrax
+		}
+
+		// dryRun sends the syrax through the webhook without creating it. The
+		// webhook reads the policies from the cache of the manager, which
+		// catches up with the policies created by a spec eventually.
+		dryRun := func(syrax *Syrax) func() error {
+			return func() error {
+				return k8sClient.Create(ctx, syrax.DeepCopy(), client.DryRunAll)
+			}
+		}
+
+		BeforeEach(func() {
+			policy := &SyraxPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: policyName, Namespace: "default"},
+				Spec: SyraxPolicySpec{
+					AllowedRegistries:   []string{"registry.example.com"},
+					MaxReplicas:         ptr.To[int32](3),
+					AllowedServiceTypes: []corev1.ServiceType{corev1.ServiceTypeClusterIP},
+					RequiredLabels:      []string{"team"},
+					MaxResources:        corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+				},
+			}
+			Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, policy))).To(Succeed())
+			})
+		})
+
+		It("Should admit a compliant syrax", func() {
+			Expect(k8sClient.Create(ctx, compliantSyrax())).To(Succeed())
+		})
+
+		It("Should reject the syraxes that violate the policy", func() {
+			syrax := compliantSyrax()
+			syrax.Spec.Labels = nil
+			syrax.Spec.DeploymentSpec.Image = "nginx:1.25"
+			syrax.Spec.DeploymentSpec.Replicas = ptr.To[int32](5)
+			syrax.Spec.DeploymentSpec.Resources.Limits[corev1.ResourceCPU] = resource.MustParse("2")
+			syrax.Spec.ServiceSpec.ServiceType = ""
+
+			Eventually(dryRun(syrax)).Should(And(
+				Satisfy(apierrors.IsInvalid),
+				MatchError(ContainSubstring("spec.labels[team]")),
+				MatchError(ContainSubstring("spec.deploymentSpec.image")),
+				MatchError(ContainSubstring("spec.deploymentSpec.replicas")),
+				MatchError(ContainSubstring("spec.deploymentSpec.resources.limits[cpu]")),
+				MatchError(ContainSubstring(`spec.serviceSpec.type: Unsupported value: "NodePort"`)),
+			))
+		})
+
+		It("Should let the updates that leave the spec alone through", func() {
+			policy := &SyraxPolicy{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: policyName, Namespace: "default"}, policy)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, policy)).To(Succeed())
+			syrax := compliantSyrax()
+			syrax.Spec.DeploymentSpec.Replicas = ptr.To[int32](5)
+			Eventually(func() error {
+				return k8sClient.Create(ctx, syrax.DeepCopy())
+			}).Should(Succeed())
+
+			By("creating the policy after the syrax")
+			policy.ResourceVersion = ""
+			Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+			updated := syrax.DeepCopy()
+			updated.Spec.DeploymentSpec.Replicas = ptr.To[int32](4)
+			Eventually(func() error {
+				return k8sClient.Update(ctx, updated.DeepCopy(), client.DryRunAll)
+			}).Should(MatchError(ContainSubstring("spec.deploymentSpec.replicas")))
+
+			syrax.Annotations = map[string]string{"note": "metadata only"}
+			Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		})
+
+		It("Should require a limit for the capped resources", func() {
+			syrax := compliantSyrax()
+			syrax.Spec.DeploymentSpec.Resources = nil
+			Eventually(dryRun(syrax)).Should(MatchError(ContainSubstring("spec.deploymentSpec.resources.limits[cpu]: Required value")))
+		})
+
+		It("Should check the syrax merged over its template", func() {
+			template := &SyraxTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "policy-defaults"},
+				Spec: SyraxTemplateSpec{
+					ImageRegistry: "quay.io/shop",
+					Labels:        map[string]string{"team": "payments"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, template)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, template)).To(Succeed())
+			})
+
+			syrax := compliantSyrax()
+			syrax.Spec.TemplateRef = &TemplateReference{Name: template.Name}
+			syrax.Spec.Labels = nil
+			syrax.Spec.DeploymentSpec.Image = "api:1.0"
+			Eventually(dryRun(syrax)).Should(And(
+				MatchError(ContainSubstring("quay.io/shop/api:1.0 does not come from a registry allowed")),
+				Not(MatchError(ContainSubstring("spec.labels[team]"))),
+			))
+
+			By("checking the syrax as it is while its template does not exist")
+			syrax.Spec.TemplateRef.Name = "missing"
+			syrax.Spec.DeploymentSpec.Image = "registry.example.com/shop/api:1.0"
+			Eventually(dryRun(syrax)).Should(MatchError(ContainSubstring("spec.labels[team]")))
+		})
+	})
+
+	Context("When checking a syrax against a SyraxPolicy", func() {
+		It("Should match the registries on path boundaries", func() {
+			policy := &SyraxPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "registries"},
+				Spec:       SyraxPolicySpec{AllowedRegistries: []string{"registry.example.com"}},
+			}
+			syrax := newSyrax()
+			syrax.Spec.DeploymentSpec.Image = "registry.example.com.evil.io/shop/api:1.0"
+			Expect(policy.Violations(syrax)).To(HaveLen(1))
+
+			syrax.Spec.DeploymentSpec.Image = "registry.example.com/shop/api@sha256:0123"
+			Expect(policy.Violations(syrax)).To(BeEmpty())
+
+			syrax.Spec.DeploymentSpec.Image = "nginx:1.25"
+			Expect(policy.Violations(syrax)).To(HaveLen(1))
+		})
+	})
+
+	Context("When updating the deletion policy", func() {
+		It("Should only let it leave Delete before the deletion", func() {
+			syrax := newSyrax()
+			syrax.Finalizers = []string{testFinalizer}
+			syrax.Spec.DeletionPolicy = DeletionPolicyDelete
+			Expect(k8sClient.Create(ctx, syrax)).To(Succeed())
+
+			By("leaving Delete before the deletion")
+			syrax.Spec.DeletionPolicy = DeletionPolicyWipeOut
+			Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+			syrax.Spec.DeletionPolicy = DeletionPolicyDelete
+			Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+
+			By("leaving Delete during the deletion")
+			Expect(k8sClient.Delete(ctx, syrax)).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+			Expect(syrax.DeletionTimestamp).NotTo(BeNil())
+			for _, policy := range []DeletionPolicy{DeletionPolicyWipeOut, ""} {
+				updated := syrax.DeepCopy()
+				updated.Spec.DeletionPolicy = policy
+				expectInvalid(k8sClient.Update(ctx, updated), "cannot be changed from Delete while the Syrax is being deleted")
+			}
+		})
+	})
+})
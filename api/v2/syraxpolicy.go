@@ -0,0 +1,165 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+)
+
+// Violations returns the fields of the Syrax that the policy forbids.
+func (p *SyraxPolicy) Violations(syrax *Syrax) field.ErrorList {
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+	for _, key := range p.Spec.RequiredLabels {
+		if _, ok := syrax.Spec.Labels[key]; !ok && !templateLabel(syrax, key) {
+			errs = append(errs, field.Required(spec.Child("labels").Key(key), fmt.Sprintf("is required by syraxpolicy %s", p.Name)))
+		}
+	}
+	errs = append(errs, p.deploymentViolations(&syrax.Spec.DeploymentSpec, spec.Child("deploymentSpec"))...)
+	errs = append(errs, p.serviceViolations(&syrax.Spec.ServiceSpec, spec.Child("serviceSpec"))...)
+	for i := range syrax.Spec.Components {
+		component := &syrax.Spec.Components[i]
+		path := spec.Child("components").Index(i)
+		errs = append(errs, p.deploymentViolations(&component.DeploymentSpec, path.Child("deploymentSpec"))...)
+		if component.ServiceSpec != nil {
+			errs = append(errs, p.serviceViolations(component.ServiceSpec, path.Child("serviceSpec"))...)
+		}
+	}
+	return errs
+}
+
+// templateLabel reports whether the template the Syrax was resolved with sets
+// the label.
+func templateLabel(syrax *Syrax, key string) bool {
+	if syrax.Status.Template == nil {
+		return false
+	}
+	_, ok := syrax.Status.Template.Labels[key]
+	return ok
+}
+
+func (p *SyraxPolicy) deploymentViolations(deployment *DeploymentSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if len(p.Spec.AllowedRegistries) > 0 && !registryAllowed(deployment.Image, p.Spec.AllowedRegistries) {
+		errs = append(errs, field.Forbidden(path.Child("image"),
+			fmt.Sprintf("%s does not come from a registry allowed by syraxpolicy %s: %s", deployment.Image, p.Name, strings.Join(p.Spec.AllowedRegistries, ", "))))
+	}
+	if p.Spec.MaxReplicas != nil {
+		// the deployment runs a single replica when none is requested.
+		replicas := int32(1)
+		if deployment.Replicas != nil {
+			replicas = *deployment.Replicas
+		}
+		if replicas > *p.Spec.MaxReplicas {
+			errs = append(errs, field.Forbidden(path.Child("replicas"),
+				fmt.Sprintf("%d is above the maximum of %d allowed by syraxpolicy %s", replicas, *p.Spec.MaxReplicas, p.Name)))
+		}
+	}
+	// without a limit, the containers could use more than the maximum.
+	resources := &corev1.ResourceRequirements{}
+	if deployment.Resources != nil {
+		resources = deployment.Resources
+	}
+	errs = append(errs, p.resourceViolations(resources.Limits, path.Child("resources", "limits"), true)...)
+	errs = append(errs, p.resourceViolations(resources.Requests, path.Child("resources", "requests"), false)...)
+	return errs
+}
+
+func (p *SyraxPolicy) resourceViolations(resources corev1.ResourceList, path *field.Path, required bool) field.ErrorList {
+	names := make([]string, 0, len(p.Spec.MaxResources))
+	for name := range p.Spec.MaxResources {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	var errs field.ErrorList
+	for _, name := range names {
+		max := p.Spec.MaxResources[corev1.ResourceName(name)]
+		quantity, ok := resources[corev1.ResourceName(name)]
+		switch {
+		case !ok && required:
+			errs = append(errs, field.Required(path.Key(name),
+				fmt.Sprintf("is required by syraxpolicy %s, which allows at most %s", p.Name, max.String())))
+		case ok && quantity.Cmp(max) > 0:
+			errs = append(errs, field.Forbidden(path.Key(name),
+				fmt.Sprintf("%s is above the maximum of %s allowed by syraxpolicy %s", quantity.String(), max.String(), p.Name)))
+		}
+	}
+	return errs
+}
+
+func (p *SyraxPolicy) serviceViolations(service *ServiceSpec, path *field.Path) field.ErrorList {
+	if len(p.Spec.AllowedServiceTypes) == 0 {
+		return nil
+	}
+	serviceType := service.ServiceType
+	if serviceType == "" {
+		serviceType = corev1.ServiceType(utils.DefaultServiceType)
+	}
+	for _, allowed := range p.Spec.AllowedServiceTypes {
+		if serviceType == allowed {
+			return nil
+		}
+	}
+	return field.ErrorList{field.NotSupported(path.Child("type"), serviceType, serviceTypeNames(p.Spec.AllowedServiceTypes))}
+}
+
+func serviceTypeNames(types []corev1.ServiceType) []string {
+	names := make([]string, 0, len(types))
+	for _, serviceType := range types {
+		names = append(names, string(serviceType))
+	}
+	return names
+}
+
+// registryAllowed reports whether the image comes from one of the registries.
+func registryAllowed(image string, registries []string) bool {
+	repository := imageRepository(image)
+	for _, registry := range registries {
+		registry = strings.TrimSuffix(registry, "/")
+		if repository == registry || strings.HasPrefix(repository, registry+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// imageRepository returns the image without its tag and digest, prefixed
+// with docker.io when it does not name a registry.
+func imageRepository(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	host, _, found := strings.Cut(image, "/")
+	if found && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		return image
+	}
+	if !found {
+		image = "library/" + image
+	}
+	return "docker.io/" + image
+}
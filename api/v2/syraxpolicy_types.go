@@ -0,0 +1,78 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SyraxPolicySpec restricts the Syraxes of the namespace of the policy. An
+// empty field does not restrict anything, and every policy of a namespace
+// applies.
+type SyraxPolicySpec struct {
+	// AllowedRegistries lists the registries the images may come from, such
+	// as registry.example.com or registry.example.com/team. Images without a
+	// registry come from docker.io.
+	// +optional
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+	// MaxReplicas caps the replicas of the deployment and of every component.
+	// It applies to the requested replicas only: the preview of a blue-green
+	// rollout runs as many pods again until the previous color is scaled
+	// down, and these extra pods are not counted.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+	// AllowedServiceTypes lists the service types the Syraxes may request. A
+	// Syrax without a service type gets a NodePort service.
+	// +optional
+	AllowedServiceTypes []corev1.ServiceType `json:"allowedServiceTypes,omitempty"`
+	// RequiredLabels lists the keys every Syrax must set in spec.labels or get
+	// from its template.
+	// +optional
+	RequiredLabels []string `json:"requiredLabels,omitempty"`
+	// MaxResources caps the resource requests and limits of the containers.
+	// The containers must set a limit for every capped resource.
+	// +optional
+	MaxResources corev1.ResourceList `json:"maxResources,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:shortName=srxpol
+
+// SyraxPolicy is the Schema for the syraxpolicies API. It is enforced by the
+// validating webhook of Syrax, and Syraxes created before it report their
+// violations in their PolicyViolation condition.
+type SyraxPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SyraxPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SyraxPolicyList contains a list of SyraxPolicy
+type SyraxPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SyraxPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SyraxPolicy{}, &SyraxPolicyList{})
+}
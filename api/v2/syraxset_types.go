@@ -0,0 +1,148 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SyraxSetSpec defines the desired state of SyraxSet
+type SyraxSetSpec struct {
+	// Generators produce the parameters of the Syraxes, one Syrax per set of
+	// parameters. The Syraxes of every generator are added up.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=10
+	Generators []SyraxSetGenerator `json:"generators"`
+	// Template is the Syrax stamped out for every set of parameters. The
+	// {{param}} placeholders in its strings, such as the name, the labels or
+	// the image tag, are replaced with the parameters.
+	Template SyraxSetTemplate `json:"template"`
+}
+
+// SyraxSetGenerator produces sets of parameters. Exactly one of its fields
+// must be set.
+// +kubebuilder:validation:XValidation:rule="[has(self.list), has(self.namespaces), has(self.configMap)].exists_one(x, x)",message="exactly one of list, namespaces and configMap must be set"
+type SyraxSetGenerator struct {
+	// List generates one set of parameters per element.
+	// +optional
+	List *ListGenerator `json:"list,omitempty"`
+	// Namespaces generates one set of parameters per namespace matching the
+	// selector, with the name of the namespace as the namespace parameter.
+	// +optional
+	Namespaces *NamespaceGenerator `json:"namespaces,omitempty"`
+	// ConfigMap generates one set of parameters per entry of a ConfigMap,
+	// with the key and value parameters.
+	// +optional
+	ConfigMap *ConfigMapGenerator `json:"configMap,omitempty"`
+}
+
+// ListGenerator lists the sets of parameters.
+type ListGenerator struct {
+	// +kubebuilder:validation:MaxItems=500
+	Elements []map[string]string `json:"elements"`
+}
+
+// NamespaceGenerator selects namespaces.
+type NamespaceGenerator struct {
+	// Selector selects the namespaces by their labels.
+	Selector metav1.LabelSelector `json:"selector"`
+}
+
+// ConfigMapGenerator reads the entries of a ConfigMap.
+type ConfigMapGenerator struct {
+	// Namespace of the ConfigMap.
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+	// Name of the ConfigMap.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// SyraxSetTemplate describes the Syraxes of a SyraxSet.
+type SyraxSetTemplate struct {
+	// Metadata of the generated Syraxes.
+	Metadata SyraxSetTemplateMeta `json:"metadata"`
+	// Spec of the generated Syraxes.
+	Spec SyraxSpec `json:"spec"`
+}
+
+// SyraxSetTemplateMeta holds the metadata of the generated Syraxes.
+type SyraxSetTemplateMeta struct {
+	// Name of the generated Syraxes. It must hold placeholders telling them
+	// apart, e.g. api-{{tenant}}.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Namespace of the generated Syraxes, e.g. {{namespace}} with the
+	// namespaces generator.
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// SyraxSetStatus defines the observed state of SyraxSet
+type SyraxSetStatus struct {
+	// ObservedGeneration is the generation of the SyraxSet last reconciled.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Syraxes is the number of Syraxes generated by the set.
+	// +optional
+	Syraxes int32 `json:"syraxes,omitempty"`
+	// ReadySyraxes is the number of generated Syraxes that are Ready.
+	// +optional
+	ReadySyraxes int32 `json:"readySyraxes,omitempty"`
+	// Conditions represent the latest available observations of the SyraxSet
+	// state. Ready is true once every generated Syrax is Ready.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster,shortName=srxset,categories=all
+//+kubebuilder:printcolumn:name="Syraxes",type=integer,JSONPath=`.status.syraxes`
+//+kubebuilder:printcolumn:name="Ready Syraxes",type=integer,JSONPath=`.status.readySyraxes`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SyraxSet is the Schema for the syraxsets API. It stamps out a Syrax for
+// every set of parameters of its generators. It is cluster-scoped, as its
+// Syraxes may live in any namespace.
+type SyraxSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SyraxSetSpec   `json:"spec,omitempty"`
+	Status SyraxSetStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SyraxSetList contains a list of SyraxSet
+type SyraxSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SyraxSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SyraxSet{}, &SyraxSetList{})
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// Apply merges the spec of the Syrax over the defaults of the template and
+// records the template in the status of the Syrax. The merged spec only lives
+// in memory and is never written back to the Syrax.
+func (t *SyraxTemplate) Apply(syrax *Syrax) error {
+	spec, err := mergeTemplate(&t.Spec, &syrax.Spec)
+	if err != nil {
+		return fmt.Errorf("unable to merge syraxtemplate %s: %w", t.Name, err)
+	}
+	syrax.Spec = *spec
+	syrax.Status.Template = &ResolvedTemplate{
+		Name:       t.Name,
+		Generation: t.Generation,
+		Labels:     t.Spec.Labels,
+	}
+	return nil
+}
+
+// mergeTemplate returns the spec merged over the defaults of the template
+// with the semantics of a strategic merge patch: fields set in the spec win,
+// resources are merged by key, env by name, and probes are replaced as a
+// whole. The deployment defaults also apply to the components. The labels of
+// the template are left out, as they must not end up in the selectors of the
+// children; they are kept in ResolvedTemplate instead.
+func mergeTemplate(template *SyraxTemplateSpec, spec *SyraxSpec) (*SyraxSpec, error) {
+	defaults := SyraxSpec{
+		Env:            template.Env,
+		DeploymentSpec: templateDeploymentSpec(template.DeploymentSpec),
+	}
+	merged := &SyraxSpec{}
+	if err := strategicMerge(defaults, spec, merged); err != nil {
+		return nil, err
+	}
+	merged.DeploymentSpec.Image = withRegistry(merged.DeploymentSpec.Image, template.ImageRegistry)
+
+	for i := range merged.Components {
+		component := &merged.Components[i]
+		deployment := DeploymentSpec{}
+		if err := strategicMerge(defaults.DeploymentSpec, component.DeploymentSpec, &deployment); err != nil {
+			return nil, err
+		}
+		deployment.Image = withRegistry(deployment.Image, template.ImageRegistry)
+		component.DeploymentSpec = deployment
+	}
+	return merged, nil
+}
+
+func templateDeploymentSpec(template *TemplateDeploymentSpec) DeploymentSpec {
+	if template == nil {
+		return DeploymentSpec{}
+	}
+	return DeploymentSpec{
+		Replicas:                template.Replicas,
+		MinReadySeconds:         template.MinReadySeconds,
+		ProgressDeadlineSeconds: template.ProgressDeadlineSeconds,
+		Resources:               template.Resources,
+		LivenessProbe:           template.LivenessProbe,
+		ReadinessProbe:          template.ReadinessProbe,
+	}
+}
+
+// strategicMerge applies obj as a strategic merge patch to defaults and
+// decodes the result into merged, whose type holds the patch strategies.
+func strategicMerge(defaults, obj, merged interface{}) error {
+	original, err := json.Marshal(defaults)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	raw, err := strategicpatch.StrategicMergePatch(original, patch, merged)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, merged)
+}
+
+// withRegistry puts the registry in front of an image that does not name one.
+func withRegistry(image, registry string) string {
+	registry = strings.TrimSuffix(registry, "/")
+	if registry == "" || image == "" {
+		return image
+	}
+	if host, _, found := strings.Cut(image, "/"); found && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		return image
+	}
+	return registry + "/" + image
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SyraxTemplateSpec holds the defaults of the Syraxes referring to the
+// template. A field set in a Syrax wins over the template.
+type SyraxTemplateSpec struct {
+	// ImageRegistry is put in front of the images that do not name a
+	// registry, e.g. registry.example.com/team.
+	// +optional
+	ImageRegistry string `json:"imageRegistry,omitempty"`
+	// Labels are put on the children of the Syrax and their pods, under the
+	// labels of the Syrax. Unlike those, they are not part of the selectors,
+	// so that they can be changed.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Env is merged by name with the env of the Syrax.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+	// DeploymentSpec holds the defaults of the deployment of the Syrax and of
+	// its components.
+	// +optional
+	DeploymentSpec *TemplateDeploymentSpec `json:"deploymentSpec,omitempty"`
+}
+
+// TemplateDeploymentSpec holds the defaults of a deployment.
+type TemplateDeploymentSpec struct {
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// +optional
+	MinReadySeconds int32 `json:"minReadySeconds,omitempty"`
+	// +optional
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+	// Resources are merged by resource name with those of the Syrax.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// LivenessProbe is used when the Syrax sets none.
+	// +optional
+	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty"`
+	// ReadinessProbe is used when the Syrax sets none.
+	// +optional
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster,shortName=srxt
+//+kubebuilder:printcolumn:name="Registry",type=string,JSONPath=`.spec.imageRegistry`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SyraxTemplate is the Schema for the syraxtemplates API. It holds the
+// organization defaults that Syraxes pick up through spec.templateRef.
+type SyraxTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SyraxTemplateSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SyraxTemplateList contains a list of SyraxTemplate
+type SyraxTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SyraxTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SyraxTemplate{}, &SyraxTemplateList{})
+}
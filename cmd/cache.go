@@ -0,0 +1,87 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+)
+
+// cacheOptions restricts the cache of the manager to the given comma-separated
+// namespaces, or to every namespace when it is empty, and, if managedOnly is
+// set, the deployment and service informers to the children of syraxes.
+//
+// The secret informers only ever cache the children of syraxes, besides every
+// secret of the registryNamespaces the registry credentials are copied from,
+// so that changes of a source reach its copies right away.
+func cacheOptions(namespaces string, managedOnly bool, syncPeriod time.Duration, registryNamespaces []string) cache.Options {
+	opts := cache.Options{
+		SyncPeriod: &syncPeriod,
+	}
+	for _, namespace := range splitNamespaces(namespaces) {
+		if opts.DefaultNamespaces == nil {
+			opts.DefaultNamespaces = map[string]cache.Config{}
+		}
+		opts.DefaultNamespaces[namespace] = cache.Config{}
+	}
+	selector := labels.SelectorFromSet(utils.DefaultLabel)
+	opts.ByObject = map[client.Object]cache.ByObject{
+		&corev1.Secret{}: {Namespaces: secretNamespaces(opts.DefaultNamespaces, selector, registryNamespaces)},
+	}
+	if managedOnly {
+		opts.ByObject[&appsv1.Deployment{}] = cache.ByObject{Label: selector}
+		opts.ByObject[&corev1.Service{}] = cache.ByObject{Label: selector}
+	}
+	return opts
+}
+
+// secretNamespaces returns the cache configs of the secrets: the children
+// selected by selector in the watched namespaces, or in every namespace if
+// none is, and every secret of the registryNamespaces.
+func secretNamespaces(watched map[string]cache.Config, selector labels.Selector, registryNamespaces []string) map[string]cache.Config {
+	namespaces := map[string]cache.Config{}
+	if len(watched) == 0 {
+		namespaces[cache.AllNamespaces] = cache.Config{LabelSelector: selector}
+	}
+	for namespace := range watched {
+		namespaces[namespace] = cache.Config{LabelSelector: selector}
+	}
+	for _, namespace := range registryNamespaces {
+		namespaces[namespace] = cache.Config{LabelSelector: labels.Everything()}
+	}
+	return namespaces
+}
+
+// splitNamespaces returns the comma-separated namespaces, skipping the empty
+// ones.
+func splitNamespaces(namespaces string) []string {
+	var names []string
+	for _, namespace := range strings.Split(namespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			names = append(names, namespace)
+		}
+	}
+	return names
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/internal/controller"
+)
+
+var _ = Describe("Manager cache", func() {
+	const centralNamespace = "registry-credentials"
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	var mgr ctrl.Manager
+
+	syraxName := types.NamespacedName{Name: "cached-resource", Namespace: "default"}
+	copyName := types.NamespacedName{Name: "cached-resource-registry-credentials", Namespace: "default"}
+
+	BeforeEach(func() {
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: centralNamespace}}
+		Expect(client.IgnoreAlreadyExists(k8sClient.Create(context.Background(), namespace))).To(Succeed())
+
+		var err error
+		mgr, err = ctrl.NewManager(cfg, ctrl.Options{
+			Scheme:  scheme,
+			Cache:   cacheOptions("default", false, 10*time.Hour, []string{centralNamespace}),
+			Metrics: metricsserver.Options{BindAddress: "0"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect((&controller.SyraxReconciler{
+			Client:    mgr.GetClient(),
+			Scheme:    mgr.GetScheme(),
+			Cache:     mgr.GetCache(),
+			APIReader: mgr.GetAPIReader(),
+			Recorder:  mgr.GetEventRecorderFor("Syrax-controller"),
+
+			RegistryCredentialsNamespaces: []string{centralNamespace},
+		}).SetupWithManager(mgr)).To(Succeed())
+
+		ctx, cancel = context.WithCancel(context.Background())
+		go func() {
+			defer GinkgoRecover()
+			Expect(mgr.Start(ctx)).To(Succeed())
+		}()
+	})
+
+	AfterEach(func() {
+		syrax := &targaryenv2.Syrax{}
+		err := k8sClient.Get(ctx, syraxName, syrax)
+		if err == nil {
+			Expect(k8sClient.Delete(ctx, syrax)).To(Succeed())
+			// the controller still runs to remove its finalizer.
+			Eventually(func() bool {
+				return apierrors.IsNotFound(k8sClient.Get(ctx, syraxName, syrax))
+			}).Should(BeTrue())
+		}
+		cancel()
+	})
+
+	It("should keep the copies of the registry credentials in sync", func() {
+		source := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "pull", Namespace: centralNamespace},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"registry.example.com":{}}}`)},
+		}
+		Expect(k8sClient.Create(ctx, source)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(context.Background(), source)).To(Succeed())
+		})
+
+		syrax := &targaryenv2.Syrax{
+			ObjectMeta: metav1.ObjectMeta{Name: syraxName.Name, Namespace: syraxName.Namespace},
+			Spec: targaryenv2.SyraxSpec{
+				DeploymentSpec: targaryenv2.DeploymentSpec{Image: "registry.example.com/shop/api:1.0"},
+				ServiceSpec:    targaryenv2.ServiceSpec{Ports: []targaryenv2.ServicePort{{Port: 80}}},
+				RegistryCredentialsRef: &targaryenv2.RegistryCredentialsReference{
+					Namespace: centralNamespace,
+					Name:      source.Name,
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, syrax)).To(Succeed())
+
+		copied := func() map[string][]byte {
+			secret := &corev1.Secret{}
+			if err := k8sClient.Get(ctx, copyName, secret); err != nil {
+				return nil
+			}
+			return secret.Data
+		}
+		Eventually(copied).Should(Equal(source.Data))
+
+		By("copying the changes of the source right away")
+		source.Data[corev1.DockerConfigJsonKey] = []byte(`{"auths":{"registry.example.com":{"auth":"dXNlcjpwYXNz"}}}`)
+		Expect(k8sClient.Update(ctx, source)).To(Succeed())
+		Eventually(copied).Should(Equal(source.Data))
+
+		By("only caching the secrets of the syraxes in the watched namespaces")
+		other := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "default"},
+			StringData: map[string]string{"token": "secret"},
+		}
+		Expect(k8sClient.Create(ctx, other)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(context.Background(), other)).To(Succeed())
+		})
+		Consistently(func() error {
+			return mgr.GetCache().Get(ctx, client.ObjectKeyFromObject(other), &corev1.Secret{})
+		}).Should(Satisfy(apierrors.IsNotFound))
+		Expect(mgr.GetCache().Get(ctx, copyName, &corev1.Secret{})).To(Succeed())
+	})
+})
@@ -17,9 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -35,6 +37,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	targaryenv1 "resource.controller.sigs/resource-controller-k8s-sigs/api/v1"
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
 	"resource.controller.sigs/resource-controller-k8s-sigs/internal/controller"
 	//+kubebuilder:scaffold:imports
 )
@@ -48,6 +51,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(targaryenv1.AddToScheme(scheme))
+	utilruntime.Must(targaryenv2.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -57,6 +61,16 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var pauseAll bool
+	var otlpEndpoint string
+	var otlpInsecure bool
+	var backoffBaseDelay time.Duration
+	var backoffMaxDelay time.Duration
+	var syncPeriod time.Duration
+	var notReadyResyncPeriod time.Duration
+	var watchNamespaces string
+	var registryCredentialsNamespaces string
+	var managedChildrenOnly bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -66,6 +80,28 @@ func main() {
 		"If set the metrics endpoint is served securely")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.BoolVar(&pauseAll, "pause-all", false,
+		"If set, the children of every Syrax are left untouched and only their status is updated.")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"The host:port of the OTLP gRPC collector the reconcile traces are exported to. Tracing is disabled if empty.")
+	flag.BoolVar(&otlpInsecure, "otlp-insecure", false,
+		"If set, the traces are exported to the OTLP collector without TLS.")
+	flag.DurationVar(&backoffBaseDelay, "backoff-base-delay", 5*time.Millisecond,
+		"The delay before the first retry of a Syrax whose reconcile failed. It doubles on every further failure.")
+	flag.DurationVar(&backoffMaxDelay, "backoff-max-delay", 5*time.Minute,
+		"The maximum delay between the retries of a Syrax whose reconcile keeps failing.")
+	flag.DurationVar(&syncPeriod, "sync-period", 10*time.Hour,
+		"The period after which every watched object is reconciled again, even without changes.")
+	flag.DurationVar(&notReadyResyncPeriod, "not-ready-resync-period", 30*time.Second,
+		"The period after which a Syrax that is not Ready is reconciled again. Zero disables it.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"The comma-separated namespaces whose Syraxes are reconciled. Every namespace is watched if empty.")
+	flag.BoolVar(&managedChildrenOnly, "managed-children-only", false,
+		"If set, only the Deployments and Services labelled as Syrax children are cached and watched.")
+	flag.StringVar(&registryCredentialsNamespaces, "registry-credentials-namespaces", "",
+		"The comma-separated namespaces the registryCredentialsRef of a Syrax may copy a Secret from. None if empty.")
+	// Pass --zap-encoder=json to switch the logs to JSON, and --zap-log-level=1
+	// or 2 to include reconcile progress and the diffs of drifted children.
 	opts := zap.Options{
 		Development: true,
 	}
@@ -94,8 +130,23 @@ func main() {
 		TLSOpts: tlsOpts,
 	})
 
+	ctx := ctrl.SetupSignalHandler()
+
+	shutdownTracing, err := setupTracing(ctx, otlpEndpoint, otlpInsecure)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			setupLog.Error(err, "unable to flush traces")
+		}
+	}()
+
+	registryNamespaces := splitNamespaces(registryCredentialsNamespaces)
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
+		Cache:  cacheOptions(watchNamespaces, managedChildrenOnly, syncPeriod, registryNamespaces),
 		Metrics: metricsserver.Options{
 			BindAddress:   metricsAddr,
 			SecureServing: secureMetrics,
@@ -112,14 +163,34 @@ func main() {
 	}
 
 	if err = (&controller.SyraxReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		Cache:                mgr.GetCache(),
+		APIReader:            mgr.GetAPIReader(),
+		Recorder:             mgr.GetEventRecorderFor("Syrax-controller"),
+		PauseAll:             pauseAll,
+		RateLimiter:          controller.NewRateLimiter(backoffBaseDelay, backoffMaxDelay),
+		NotReadyResyncPeriod: notReadyResyncPeriod,
+
+		RegistryCredentialsNamespaces: registryNamespaces,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Syrax")
+		os.Exit(1)
+	}
+	if err = (&controller.SyraxSetReconciler{
 		Client:   mgr.GetClient(),
 		Scheme:   mgr.GetScheme(),
-		Cache:    mgr.GetCache(),
-		Recorder: mgr.GetEventRecorderFor("Syrax-controller"),
+		Recorder: mgr.GetEventRecorderFor("SyraxSet-controller"),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Syrax")
+		setupLog.Error(err, "unable to create controller", "controller", "SyraxSet")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&targaryenv2.Syrax{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Syrax")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -132,7 +203,7 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
@@ -0,0 +1,50 @@
+#!/usr/bin/env bash
+# Splits the ClusterRole generated by controller-gen into one Role per
+# namespace and a ClusterRole, for managers started with --watch-namespaces.
+#
+# Roles cannot grant access to cluster-scoped resources, so the rules on the
+# namespaces and on the CRDs with a Cluster scope go to the ClusterRole and
+# the other rules to the Roles.
+#
+# The SyraxSets create their Syraxes in the namespaces they select, which must
+# all be listed in WATCH_NAMESPACES: the manager has no Role in the others.
+#
+# usage: hack/namespaced-rbac.sh <comma-separated namespaces> <role.yaml> [crd dir]
+set -euo pipefail
+
+YQ="${YQ:-yq}"
+namespaces="$1"
+role="$2"
+crds="${3:-config/crd/bases}"
+
+if [ -z "${namespaces}" ]; then
+	echo "WATCH_NAMESPACES must list the namespaces to generate Roles for" >&2
+	exit 1
+fi
+
+# the cluster-scoped resources the manager may be granted access to.
+CLUSTER_SCOPED="$("${YQ}" eval-all -o=json -I=0 \
+	'[select(.spec.scope == "Cluster") | .spec.names.plural] + ["namespaces"]' "${crds}"/*.yaml)"
+export CLUSTER_SCOPED
+
+# clusterScoped is true for the resources, or their subresources, listed in
+# CLUSTER_SCOPED.
+clusterScoped='(split("/") | .[0]) as $resource | env(CLUSTER_SCOPED) | any_c(. == $resource)'
+
+cluster_role="$(sed -e '/^---$/d' "${role}")"
+{
+	for namespace in ${namespaces//,/ }; do
+		echo "---"
+		NAMESPACE="${namespace}" "${YQ}" eval "
+			.kind = \"Role\" |
+			.metadata.namespace = strenv(NAMESPACE) |
+			.rules |= (map(.resources |= map(select(${clusterScoped} | not))) | map(select(.resources | length > 0)))
+		" - <<<"${cluster_role}"
+	done
+	echo "---"
+	"${YQ}" eval "
+		.metadata.name = \"manager-cluster-role\" |
+		.rules |= (map(.resources |= map(select(${clusterScoped}))) | map(select(.resources | length > 0)))
+	" - <<<"${cluster_role}"
+} >"${role}.tmp"
+mv "${role}.tmp" "${role}"
@@ -0,0 +1,339 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	namespcedname "k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// defaultScaleDownDelay is how long the previous color keeps running after a
+// promotion when the syrax does not set one.
+const defaultScaleDownDelay = 30 * time.Second
+
+// blueGreenEnabled reports whether pod template changes of the syrax go
+// through a blue-green rollout.
+func blueGreenEnabled(syrax *syraxv2.Syrax) bool {
+	return syrax.Spec.Rollout != nil && syrax.Spec.Rollout.Mode == syraxv2.RolloutModeBlueGreen
+}
+
+// colorDeploymentName returns the name of the deployment of the given color.
+// The blue deployment is the primary one.
+func colorDeploymentName(deploymentName, color string) string {
+	if color == utils.ColorBlue {
+		return deploymentName
+	}
+	return deploymentName + "-" + color
+}
+
+func previewServiceName(serviceName string) string {
+	return serviceName + "-preview"
+}
+
+func otherColor(color string) string {
+	if color == utils.ColorBlue {
+		return utils.ColorGreen
+	}
+	return utils.ColorBlue
+}
+
+// reconcileBlueGreen keeps the active color serving the current pod template.
+// A changed template is brought up in the other color behind a preview
+// service, and the main service is switched over once the preview is ready
+// and approved. The previous color is scaled down after the scale-down delay.
+func (r *SyraxReconciler) reconcileBlueGreen(ctx context.Context, syrax *syraxv2.Syrax, deploymentName, serviceName string, deployment *appsv1.Deployment) (ctrl.Result, error) {
+	total := desiredReplicas(syrax)
+	status := syrax.Status.BlueGreen
+
+	if status == nil {
+		// label the primary pods blue before the service starts selecting by color.
+		desired := deployment.DeepCopy()
+		r.newColorDeployment(syrax, deploymentName, utils.ColorBlue, total, desired)
+		if templateChanged(desired, deployment) || ifDeployUpdated(syrax, deployment) {
+			r.newColorDeployment(syrax, deploymentName, utils.ColorBlue, total, deployment)
+			return ctrl.Result{}, r.updateChild(ctx, deployment)
+		}
+		if !rolloutComplete(deployment) {
+			return ctrl.Result{}, nil
+		}
+		// the service selects by the active color once it is persisted.
+		return ctrl.Result{}, r.patchSyraxStatus(ctx, syrax, func(syrax *syraxv2.Syrax) {
+			syrax.Status.BlueGreen = &syraxv2.BlueGreenStatus{ActiveColor: utils.ColorBlue}
+		})
+	}
+
+	activeName := colorDeploymentName(deploymentName, status.ActiveColor)
+	active, err := r.getColorDeployment(ctx, syrax, activeName, deployment)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if active == nil {
+		active = &appsv1.Deployment{}
+		r.newColorDeployment(syrax, activeName, status.ActiveColor, total, active)
+		return ctrl.Result{}, r.createChild(ctx, active)
+	}
+
+	previewColor := otherColor(status.ActiveColor)
+	previewName := colorDeploymentName(deploymentName, previewColor)
+	preview, err := r.getColorDeployment(ctx, syrax, previewName, deployment)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	desired := active.DeepCopy()
+	r.newColorDeployment(syrax, activeName, status.ActiveColor, total, desired)
+	if !templateChanged(desired, active) {
+		if ifDeployUpdated(syrax, active) || active.Spec.Replicas == nil || *active.Spec.Replicas != total {
+			r.newColorDeployment(syrax, activeName, status.ActiveColor, total, active)
+			if err = r.updateChild(ctx, active); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		if preview != nil {
+			// the previous color keeps serving until the delay has passed and
+			// the main service selects the active color.
+			if status.ScaleDownAt != nil {
+				if remaining := time.Until(status.ScaleDownAt.Time); remaining > 0 {
+					return ctrl.Result{RequeueAfter: remaining}, nil
+				}
+				switched, err := r.serviceSelectsColor(ctx, syrax, serviceName, status.ActiveColor)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				if !switched {
+					return ctrl.Result{Requeue: true}, nil
+				}
+			}
+			if err = r.scaleDeployment(ctx, preview, 0); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		status.PreviewImage = ""
+		status.PreviewReadySince = nil
+		status.ScaleDownAt = nil
+		return ctrl.Result{}, r.deletePreviewService(ctx, syrax, serviceName)
+	}
+	// the previous color is reused for the new template.
+	status.ScaleDownAt = nil
+
+	if preview == nil {
+		preview = &appsv1.Deployment{}
+		r.newColorDeployment(syrax, previewName, previewColor, total, preview)
+		err = r.createChild(ctx, preview)
+	} else {
+		desired = preview.DeepCopy()
+		r.newColorDeployment(syrax, previewName, previewColor, total, desired)
+		if templateChanged(desired, preview) || preview.Spec.Replicas == nil || *preview.Spec.Replicas != total {
+			r.newColorDeployment(syrax, previewName, previewColor, total, preview)
+			status.PreviewReadySince = nil
+			err = r.updateChild(ctx, preview)
+		}
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err = r.applyPreviewService(ctx, syrax, serviceName, previewColor); err != nil {
+		return ctrl.Result{}, err
+	}
+	status.PreviewImage = syrax.Spec.DeploymentSpec.Image
+
+	if !rolloutComplete(preview) {
+		status.PreviewReadySince = nil
+		return ctrl.Result{}, nil
+	}
+	if status.PreviewReadySince == nil {
+		now := metav1.Now()
+		status.PreviewReadySince = &now
+	}
+
+	if syrax.Annotations[utils.PromoteAnnotation] != "true" {
+		autoPromote := syrax.Spec.Rollout.BlueGreen
+		if autoPromote == nil || autoPromote.AutoPromoteAfter == nil {
+			return ctrl.Result{}, nil
+		}
+		if remaining := autoPromote.AutoPromoteAfter.Duration - time.Since(status.PreviewReadySince.Time); remaining > 0 {
+			return ctrl.Result{RequeueAfter: remaining}, nil
+		}
+	}
+
+	// the service is switched to the preview color by this reconcile, which
+	// must not be undone by a later one if the status update fails. The
+	// previous color is scaled down by a later reconcile.
+	delay := scaleDownDelay(syrax)
+	scaleDownAt := metav1.NewTime(time.Now().Add(delay))
+	if err = r.patchSyraxStatus(ctx, syrax, func(syrax *syraxv2.Syrax) {
+		syrax.Status.BlueGreen.ActiveColor = previewColor
+		syrax.Status.BlueGreen.PreviewImage = ""
+		syrax.Status.BlueGreen.PreviewReadySince = nil
+		syrax.Status.BlueGreen.ScaleDownAt = &scaleDownAt
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err = r.deletePreviewService(ctx, syrax, serviceName); err != nil {
+		return ctrl.Result{}, err
+	}
+	if _, ok := syrax.Annotations[utils.PromoteAnnotation]; ok {
+		if err = r.updateSyraxMetadata(ctx, syrax, func(syrax *syraxv2.Syrax) {
+			delete(syrax.Annotations, utils.PromoteAnnotation)
+		}); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	r.normalEvent(syrax, ReasonBlueGreenPromoted, fmt.Sprintf("the %s deployment running image %s now serves traffic", previewColor, syrax.Spec.DeploymentSpec.Image))
+	log.FromContext(ctx).Info("Promoted preview deployment", "color", previewColor, "image", syrax.Spec.DeploymentSpec.Image)
+	return ctrl.Result{RequeueAfter: delay}, nil
+}
+
+// scaleDownDelay returns how long the previous color keeps running after a
+// promotion.
+func scaleDownDelay(syrax *syraxv2.Syrax) time.Duration {
+	if blueGreen := syrax.Spec.Rollout.BlueGreen; blueGreen != nil && blueGreen.ScaleDownDelay != nil {
+		return blueGreen.ScaleDownDelay.Duration
+	}
+	return defaultScaleDownDelay
+}
+
+// serviceSelectsColor reports whether the main service only sends traffic to
+// the pods of the given color.
+func (r *SyraxReconciler) serviceSelectsColor(ctx context.Context, syrax *syraxv2.Syrax, serviceName, color string) (bool, error) {
+	service := &corev1.Service{}
+	if err := r.Get(ctx, namespcedname.NamespacedName{Namespace: syrax.Namespace, Name: serviceName}, service); err != nil {
+		return false, err
+	}
+	return service.Spec.Selector[utils.ColorLabel] == color, nil
+}
+
+// abortBlueGreen returns a syrax that left the BlueGreen rollout mode to a
+// single primary deployment. The green deployment is kept until the primary
+// is fully available so that the service always has endpoints.
+func (r *SyraxReconciler) abortBlueGreen(ctx context.Context, syrax *syraxv2.Syrax, deploymentName, serviceName string, deployment *appsv1.Deployment) error {
+	if syrax.Status.BlueGreen == nil {
+		return nil
+	}
+	if err := r.deletePreviewService(ctx, syrax, serviceName); err != nil {
+		return err
+	}
+	if !rolloutComplete(deployment) || ifDeployUpdated(syrax, deployment) {
+		return nil
+	}
+	green := &appsv1.Deployment{}
+	green.Name = colorDeploymentName(deploymentName, utils.ColorGreen)
+	green.Namespace = syrax.Namespace
+	if err := r.deleteChild(ctx, green); err != nil {
+		return err
+	}
+	syrax.Status.BlueGreen = nil
+	return nil
+}
+
+func (r *SyraxReconciler) getColorDeployment(ctx context.Context, syrax *syraxv2.Syrax, name string, primary *appsv1.Deployment) (*appsv1.Deployment, error) {
+	if name == primary.Name {
+		return primary, nil
+	}
+	deployment := &appsv1.Deployment{}
+	err := r.Get(ctx, namespcedname.NamespacedName{Namespace: syrax.Namespace, Name: name}, deployment)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return deployment, err
+}
+
+func (r *SyraxReconciler) newColorDeployment(syrax *syraxv2.Syrax, name, color string, replicas int32, deployment *appsv1.Deployment) {
+	r.newDeployment(syrax, name, deployment)
+	deployment.Spec.Replicas = ptr.To(replicas)
+
+	podLabels := make(map[string]string)
+	for k, v := range deployment.Spec.Template.Labels {
+		podLabels[k] = v
+	}
+	podLabels[utils.ColorLabel] = color
+	deployment.Spec.Template.Labels = podLabels
+	if color == utils.ColorBlue {
+		// the selector of the primary deployment is immutable and stays color-less.
+		return
+	}
+
+	labels := make(map[string]string)
+	for k, v := range deployment.Labels {
+		labels[k] = v
+	}
+	labels[utils.TrackLabel] = color
+	deployment.Labels = labels
+	selector := make(map[string]string)
+	for k, v := range deployment.Spec.Selector.MatchLabels {
+		selector[k] = v
+	}
+	selector[utils.ColorLabel] = color
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: selector}
+}
+
+// applyPreviewService exposes the pods of the preview color through a
+// ClusterIP service next to the main one.
+func (r *SyraxReconciler) applyPreviewService(ctx context.Context, syrax *syraxv2.Syrax, serviceName, color string) error {
+	name := previewServiceName(serviceName)
+	service := &corev1.Service{}
+	err := r.Get(ctx, namespcedname.NamespacedName{Namespace: syrax.Namespace, Name: name}, service)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	desired := service.DeepCopy()
+	r.newPreviewService(syrax, name, color, desired)
+	if !exists {
+		return r.createChild(ctx, desired)
+	}
+	if equality.Semantic.DeepEqual(desired.Spec.Selector, service.Spec.Selector) &&
+		equality.Semantic.DeepDerivative(desired.Spec.Ports, service.Spec.Ports) {
+		return nil
+	}
+	return r.updateChild(ctx, desired)
+}
+
+func (r *SyraxReconciler) newPreviewService(syrax *syraxv2.Syrax, name, color string, service *corev1.Service) {
+	r.newService(syrax, name, service)
+	service.Spec.Type = corev1.ServiceTypeClusterIP
+	for i := range service.Spec.Ports {
+		service.Spec.Ports[i].NodePort = 0
+	}
+	selector := syraxLabels(syrax)
+	labels := make(map[string]string)
+	for k, v := range service.Labels {
+		labels[k] = v
+	}
+	selector[utils.ColorLabel] = color
+	labels[utils.TrackLabel] = utils.TrackPreview
+	service.Spec.Selector = selector
+	service.Labels = labels
+}
+
+func (r *SyraxReconciler) deletePreviewService(ctx context.Context, syrax *syraxv2.Syrax, serviceName string) error {
+	service := &corev1.Service{}
+	service.Name = previewServiceName(serviceName)
+	service.Namespace = syrax.Namespace
+	return r.deleteChild(ctx, service)
+}
+
+// servingDeployment returns the deployment whose pods the main service sends
+// traffic to, which is the green one after a blue-green promotion.
+func (r *SyraxReconciler) servingDeployment(ctx context.Context, syrax *syraxv2.Syrax, deployment *appsv1.Deployment) *appsv1.Deployment {
+	if !blueGreenEnabled(syrax) || syrax.Status.BlueGreen == nil || syrax.Status.BlueGreen.ActiveColor == utils.ColorBlue {
+		return deployment
+	}
+	active, err := r.getColorDeployment(ctx, syrax, colorDeploymentName(deployment.Name, syrax.Status.BlueGreen.ActiveColor), deployment)
+	if err != nil || active == nil {
+		return deployment
+	}
+	return active
+}
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+)
+
+var _ = Describe("Syrax blue-green rollout", func() {
+	const resourceName = "bluegreen-resource"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+
+	var controllerReconciler *SyraxReconciler
+
+	ownedService := func() *corev1.Service {
+		services := &corev1.ServiceList{}
+		ExpectWithOffset(1, k8sClient.List(ctx, services, client.InNamespace("default"))).To(Succeed())
+		for i := range services.Items {
+			owner := metav1.GetControllerOf(&services.Items[i])
+			if owner != nil && owner.Name == resourceName && services.Items[i].Labels[utils.TrackLabel] == "" {
+				return &services.Items[i]
+			}
+		}
+		Fail("the syrax has no service")
+		return nil
+	}
+
+	BeforeEach(func() {
+		controllerReconciler = newReconciler()
+		resource := newSyrax(typeNamespacedName)
+		resource.Spec.DeploymentSpec.Replicas = ptr.To[int32](2)
+		resource.Spec.Rollout = &targaryenv2.RolloutSpec{
+			Mode:      targaryenv2.RolloutModeBlueGreen,
+			BlueGreen: &targaryenv2.BlueGreenSpec{ScaleDownDelay: &metav1.Duration{Duration: time.Hour}},
+		}
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+	})
+
+	It("should switch the service to the green deployment once promoted", func() {
+		By("labelling the primary pods blue")
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		blue := ownedDeployment(ctx, typeNamespacedName)
+		Expect(blue.Spec.Template.Labels).To(HaveKeyWithValue(utils.ColorLabel, utils.ColorBlue))
+		markRolledOut(ctx, blue)
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		// recording the last known-good template bumps the generation.
+		markRolledOut(ctx, ownedDeployment(ctx, typeNamespacedName))
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(ownedService().Spec.Selector).To(HaveKeyWithValue(utils.ColorLabel, utils.ColorBlue))
+
+		By("changing the image")
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Spec.DeploymentSpec.Image = "nginx:1.26"
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		green := &appsv1.Deployment{}
+		greenKey := types.NamespacedName{Namespace: "default", Name: colorDeploymentName(blue.Name, utils.ColorGreen)}
+		Expect(k8sClient.Get(ctx, greenKey, green)).To(Succeed())
+		Expect(green.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:1.26"))
+		service := ownedService()
+		preview := &corev1.Service{}
+		previewKey := types.NamespacedName{Namespace: "default", Name: previewServiceName(service.Name)}
+		Expect(k8sClient.Get(ctx, previewKey, preview)).To(Succeed())
+		Expect(preview.Spec.Selector).To(HaveKeyWithValue(utils.ColorLabel, utils.ColorGreen))
+
+		By("waiting for approval once the green pods are ready")
+		markRolledOut(ctx, green)
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(ownedService().Spec.Selector).To(HaveKeyWithValue(utils.ColorLabel, utils.ColorBlue))
+
+		By("approving the promotion")
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Annotations = map[string]string{utils.PromoteAnnotation: "true"}
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		result := reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(result.RequeueAfter).To(Equal(time.Hour))
+
+		Expect(ownedService().Spec.Selector).To(HaveKeyWithValue(utils.ColorLabel, utils.ColorGreen))
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, previewKey, preview))).To(BeTrue())
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(syrax.Annotations).NotTo(HaveKey(utils.PromoteAnnotation))
+		Expect(syrax.Status.BlueGreen.ActiveColor).To(Equal(utils.ColorGreen))
+		Expect(syrax.Status.BlueGreen.ScaleDownAt).NotTo(BeNil())
+		Expect(syrax.Spec.ServiceSpec.ServiceType).To(BeEmpty())
+
+		By("keeping the blue pods until the scale-down delay has passed")
+		// the service selects green pods that are available, so it has endpoints.
+		Expect(k8sClient.Get(ctx, greenKey, green)).To(Succeed())
+		Expect(*green.Spec.Replicas).To(Equal(int32(2)))
+		Expect(green.Status.AvailableReplicas).To(Equal(int32(2)))
+		Expect(*ownedDeployment(ctx, typeNamespacedName).Spec.Replicas).To(Equal(int32(2)))
+		result = reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		Expect(*ownedDeployment(ctx, typeNamespacedName).Spec.Replicas).To(Equal(int32(2)))
+
+		By("scaling the blue pods down once the delay has passed")
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Status.BlueGreen.ScaleDownAt = &metav1.Time{Time: time.Now().Add(-time.Second)}
+		Expect(k8sClient.Status().Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(ownedService().Spec.Selector).To(HaveKeyWithValue(utils.ColorLabel, utils.ColorGreen))
+		Expect(*ownedDeployment(ctx, typeNamespacedName).Spec.Replicas).To(Equal(int32(0)))
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(syrax.Status.BlueGreen.ScaleDownAt).To(BeNil())
+	})
+})
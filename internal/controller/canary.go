@@ -0,0 +1,234 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	namespcedname "k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// canaryEnabled reports whether pod template changes of the syrax go through
+// a canary deployment.
+func canaryEnabled(syrax *syraxv2.Syrax) bool {
+	return syrax.Spec.Rollout != nil && syrax.Spec.Rollout.Mode == syraxv2.RolloutModeCanary &&
+		syrax.Spec.Rollout.Canary != nil && len(syrax.Spec.Rollout.Canary.Steps) > 0
+}
+
+func canaryName(deploymentName string) string {
+	return deploymentName + "-" + utils.TrackCanary
+}
+
+// reconcileCanary moves a changed pod template through the canary steps of
+// the syrax. The primary deployment keeps the old template until the canary is
+// promoted, and gives up as many replicas as the canary runs.
+func (r *SyraxReconciler) reconcileCanary(ctx context.Context, syrax *syraxv2.Syrax, deploymentName string, deployment *appsv1.Deployment) (ctrl.Result, error) {
+	desired := deployment.DeepCopy()
+	r.newDeployment(syrax, deploymentName, desired)
+	total := desiredReplicas(syrax)
+	steps := syrax.Spec.Rollout.Canary.Steps
+
+	if syrax.DeletionTimestamp != nil || !templateChanged(desired, deployment) {
+		if err := r.abortCanary(ctx, syrax, deploymentName); err != nil {
+			return ctrl.Result{}, err
+		}
+		if ifDeployUpdated(syrax, deployment) && !rolloutBlocked(syrax) {
+			r.newDeployment(syrax, deploymentName, deployment)
+			return ctrl.Result{}, r.updateChild(ctx, deployment)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	status := syrax.Status.Canary
+	image := syrax.Spec.DeploymentSpec.Image
+	if status == nil || (status.ObservedGeneration != syrax.Generation && (status.Phase != syraxv2.CanaryPhaseProgressing || status.Image != image)) {
+		status = &syraxv2.CanaryStatus{Phase: syraxv2.CanaryPhaseProgressing, Image: image}
+		syrax.Status.Canary = status
+	}
+	status.ObservedGeneration = syrax.Generation
+
+	if status.Phase == syraxv2.CanaryPhaseAborted {
+		if err := r.deleteCanary(ctx, syrax, deploymentName); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.scaleDeployment(ctx, deployment, total)
+	}
+	if int(status.CurrentStep) >= len(steps) {
+		status.CurrentStep = int32(len(steps)) - 1
+	}
+
+	replicas := canaryReplicas(total, steps[status.CurrentStep].Weight)
+	canary, err := r.applyCanary(ctx, syrax, canaryName(deploymentName), replicas)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	status.Replicas = replicas
+	status.AvailableReplicas = canary.Status.AvailableReplicas
+
+	if rolloutFailed(canary) {
+		message := fmt.Sprintf("canary of image %s exceeded its progress deadline and was aborted", image)
+		status.Phase = syraxv2.CanaryPhaseAborted
+		status.StepStartedAt = nil
+		if err = r.deleteCanary(ctx, syrax, deploymentName); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.warningEvent(syrax, ReasonCanaryAborted, message)
+		log.FromContext(ctx).Info("Aborted canary", "image", image, "step", status.CurrentStep)
+		return ctrl.Result{}, r.scaleDeployment(ctx, deployment, total)
+	}
+	if err = r.scaleDeployment(ctx, deployment, total-replicas); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !canaryReady(canary, replicas) {
+		// the canary deployment is owned by the syrax, its status changes requeue it.
+		return ctrl.Result{}, nil
+	}
+	if status.StepStartedAt == nil {
+		now := metav1.Now()
+		status.StepStartedAt = &now
+	}
+	if pause := steps[status.CurrentStep].Pause; pause != nil {
+		if remaining := pause.Duration - time.Since(status.StepStartedAt.Time); remaining > 0 {
+			return ctrl.Result{RequeueAfter: remaining}, nil
+		}
+	}
+
+	status.StepStartedAt = nil
+	if int(status.CurrentStep) < len(steps)-1 {
+		status.CurrentStep++
+		log.FromContext(ctx).V(1).Info("Advanced canary step", "image", image, "step", status.CurrentStep, "weight", steps[status.CurrentStep].Weight)
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	r.newDeployment(syrax, deploymentName, deployment)
+	if err = r.updateChild(ctx, deployment); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err = r.deleteCanary(ctx, syrax, deploymentName); err != nil {
+		return ctrl.Result{}, err
+	}
+	status.Phase = syraxv2.CanaryPhasePromoted
+	r.normalEvent(syrax, ReasonCanaryPromoted, fmt.Sprintf("canary of image %s was promoted", image))
+	log.FromContext(ctx).Info("Promoted canary", "image", image)
+	return ctrl.Result{}, nil
+}
+
+// applyCanary creates or updates the canary deployment with the desired pod
+// template and replica count.
+func (r *SyraxReconciler) applyCanary(ctx context.Context, syrax *syraxv2.Syrax, name string, replicas int32) (*appsv1.Deployment, error) {
+	canary := &appsv1.Deployment{}
+	err := r.Get(ctx, namespcedname.NamespacedName{Namespace: syrax.Namespace, Name: name}, canary)
+	if errors.IsNotFound(err) {
+		r.newCanaryDeployment(syrax, name, replicas, canary)
+		return canary, r.createChild(ctx, canary)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	desired := canary.DeepCopy()
+	r.newCanaryDeployment(syrax, name, replicas, desired)
+	if templateChanged(desired, canary) || canary.Spec.Replicas == nil || *canary.Spec.Replicas != replicas {
+		r.newCanaryDeployment(syrax, name, replicas, canary)
+		return canary, r.updateChild(ctx, canary)
+	}
+	return canary, nil
+}
+
+func (r *SyraxReconciler) newCanaryDeployment(syrax *syraxv2.Syrax, name string, replicas int32, deployment *appsv1.Deployment) {
+	r.newDeployment(syrax, name, deployment)
+	deployment.Spec.Replicas = ptr.To(replicas)
+
+	labels := make(map[string]string)
+	for k, v := range deployment.Labels {
+		labels[k] = v
+	}
+	labels[utils.TrackLabel] = utils.TrackCanary
+	deployment.Labels = labels
+	selector := make(map[string]string)
+	for k, v := range deployment.Spec.Selector.MatchLabels {
+		selector[k] = v
+	}
+	selector[utils.TrackLabel] = utils.TrackCanary
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: selector}
+	podLabels := make(map[string]string)
+	for k, v := range deployment.Spec.Template.Labels {
+		podLabels[k] = v
+	}
+	podLabels[utils.TrackLabel] = utils.TrackCanary
+	deployment.Spec.Template.Labels = podLabels
+}
+
+// abortCanary removes a canary that is still in progress, e.g. because the
+// pod template was reverted or the rollout mode changed.
+func (r *SyraxReconciler) abortCanary(ctx context.Context, syrax *syraxv2.Syrax, deploymentName string) error {
+	if syrax.Status.Canary == nil || syrax.Status.Canary.Phase != syraxv2.CanaryPhaseProgressing {
+		return nil
+	}
+	if err := r.deleteCanary(ctx, syrax, deploymentName); err != nil {
+		return err
+	}
+	syrax.Status.Canary.Phase = syraxv2.CanaryPhaseAborted
+	syrax.Status.Canary.StepStartedAt = nil
+	r.normalEvent(syrax, ReasonCanaryAborted, fmt.Sprintf("canary of image %s was abandoned", syrax.Status.Canary.Image))
+	return nil
+}
+
+func (r *SyraxReconciler) deleteCanary(ctx context.Context, syrax *syraxv2.Syrax, deploymentName string) error {
+	canary := &appsv1.Deployment{}
+	canary.Name = canaryName(deploymentName)
+	canary.Namespace = syrax.Namespace
+	return r.deleteChild(ctx, canary)
+}
+
+// scaleDeployment sets the replica count of a deployment without touching its
+// pod template.
+func (r *SyraxReconciler) scaleDeployment(ctx context.Context, deployment *appsv1.Deployment, replicas int32) error {
+	if deployment.Spec.Replicas != nil && *deployment.Spec.Replicas == replicas {
+		return nil
+	}
+	deployment.Spec.Replicas = ptr.To(replicas)
+	return r.updateChild(ctx, deployment)
+}
+
+// canaryReplicas returns how many of the total replicas run the canary at the
+// given weight, rounding up so that any non-zero weight gets a pod.
+func canaryReplicas(total, weight int32) int32 {
+	if weight <= 0 || total <= 0 {
+		return 0
+	}
+	replicas := (total*weight + 99) / 100
+	if replicas > total {
+		return total
+	}
+	return replicas
+}
+
+func canaryReady(canary *appsv1.Deployment, replicas int32) bool {
+	return canary.Status.ObservedGeneration >= canary.Generation &&
+		canary.Status.UpdatedReplicas == replicas &&
+		canary.Status.AvailableReplicas == replicas
+}
+
+func desiredReplicas(syrax *syraxv2.Syrax) int32 {
+	if syrax.Spec.DeploymentSpec.Replicas == nil {
+		return 1
+	}
+	return *syrax.Spec.DeploymentSpec.Replicas
+}
+
+// templateChanged reports whether the pod template built for the syrax differs
+// from the one of the deployment. Fields defaulted by the API server are ignored.
+func templateChanged(desired, current *appsv1.Deployment) bool {
+	return !equality.Semantic.DeepDerivative(desired.Spec.Template, current.Spec.Template)
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+)
+
+var _ = Describe("Syrax canary rollout", func() {
+	const resourceName = "canary-resource"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+
+	var controllerReconciler *SyraxReconciler
+
+	BeforeEach(func() {
+		controllerReconciler = newReconciler()
+		resource := newSyrax(typeNamespacedName)
+		resource.Spec.DeploymentSpec.Replicas = ptr.To[int32](4)
+		resource.Spec.Rollout = &targaryenv2.RolloutSpec{
+			Mode: targaryenv2.RolloutModeCanary,
+			Canary: &targaryenv2.CanarySpec{
+				Steps: []targaryenv2.CanaryStep{{Weight: 25}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+	})
+
+	It("should shift replicas to a canary and promote it once ready", func() {
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		primary := ownedDeployment(ctx, typeNamespacedName)
+
+		By("changing the image")
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Spec.DeploymentSpec.Image = "nginx:1.26"
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		canary := &appsv1.Deployment{}
+		canaryKey := types.NamespacedName{Namespace: "default", Name: canaryName(primary.Name)}
+		Expect(k8sClient.Get(ctx, canaryKey, canary)).To(Succeed())
+		Expect(*canary.Spec.Replicas).To(Equal(int32(1)))
+		Expect(canary.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:1.26"))
+
+		primary = ownedDeployment(ctx, typeNamespacedName)
+		Expect(*primary.Spec.Replicas).To(Equal(int32(3)))
+		Expect(primary.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:1.25"))
+
+		By("marking the canary pods ready")
+		markRolledOut(ctx, canary)
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		primary = ownedDeployment(ctx, typeNamespacedName)
+		Expect(*primary.Spec.Replicas).To(Equal(int32(4)))
+		Expect(primary.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:1.26"))
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, canaryKey, canary))).To(BeTrue())
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(syrax.Status.Canary).NotTo(BeNil())
+		Expect(syrax.Status.Canary.Phase).To(Equal(targaryenv2.CanaryPhasePromoted))
+	})
+
+	It("should requeue right away to advance a step", func() {
+		controllerReconciler.NotReadyResyncPeriod = time.Minute
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Spec.Rollout.Canary.Steps = []targaryenv2.CanaryStep{{Weight: 25}, {Weight: 50}}
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		primary := ownedDeployment(ctx, typeNamespacedName)
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Spec.DeploymentSpec.Image = "nginx:1.26"
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		canary := &appsv1.Deployment{}
+		canaryKey := types.NamespacedName{Namespace: "default", Name: canaryName(primary.Name)}
+		Expect(k8sClient.Get(ctx, canaryKey, canary)).To(Succeed())
+		markRolledOut(ctx, canary)
+		result := reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(result.Requeue).To(BeTrue())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(syrax.Status.Canary.CurrentStep).To(Equal(int32(1)))
+	})
+})
@@ -0,0 +1,227 @@
+package controller
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	namespcedname "k8s.io/apimachinery/pkg/types"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// componentName returns the name of the deployment and service of a
+// component, the syrax name and the component name joined by a dash.
+func componentName(syrax *syraxv2.Syrax, component *syraxv2.Component) string {
+	return strings.TrimSuffix(ToLowerCase(syrax.Name), "-") + "-" + component.Name
+}
+
+// componentView returns the syrax as seen by the children of a component, so
+// that they are built like the main ones. Components always roll out with
+// the strategy of their deployment.
+func componentView(syrax *syraxv2.Syrax, component *syraxv2.Component) *syraxv2.Syrax {
+	view := syrax.DeepCopy()
+	view.Spec.DeploymentSpec = *component.DeploymentSpec.DeepCopy()
+	view.Spec.ServiceSpec = syraxv2.ServiceSpec{}
+	if component.ServiceSpec != nil {
+		view.Spec.ServiceSpec = *component.ServiceSpec.DeepCopy()
+	}
+	view.Spec.Rollout = nil
+	view.Spec.Rollback = nil
+	view.Spec.Components = nil
+	setDefaultFields(view)
+	return view
+}
+
+// componentPodLabels returns the labels of the pods of a component. The
+// default label is left out so that neither the main deployment nor the main
+// service select them.
+func componentPodLabels(syrax *syraxv2.Syrax, name string) map[string]string {
+	labels := make(map[string]string)
+	for k, v := range syrax.Spec.Labels {
+		labels[k] = v
+	}
+	for k := range utils.DefaultLabel {
+		delete(labels, k)
+	}
+	labels[utils.ComponentLabel] = name
+	return labels
+}
+
+// componentLabels returns the labels of the children of a component.
+func componentLabels(syrax *syraxv2.Syrax, name string) map[string]string {
+	labels := syraxLabels(syrax)
+	labels[utils.ComponentLabel] = name
+	return labels
+}
+
+func (r *SyraxReconciler) newComponentDeployment(syrax *syraxv2.Syrax, component *syraxv2.Component, name string, deployment *appsv1.Deployment) {
+	r.newDeployment(componentView(syrax, component), name, deployment)
+	podLabels := componentPodLabels(syrax, name)
+	deployment.Labels = withTemplateLabels(syrax, componentLabels(syrax, name))
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: podLabels}
+	deployment.Spec.Template.Labels = withTemplateLabels(syrax, podLabels)
+}
+
+func (r *SyraxReconciler) newComponentService(syrax *syraxv2.Syrax, component *syraxv2.Component, name string, service *corev1.Service) {
+	r.newService(componentView(syrax, component), name, service)
+	service.Labels = withTemplateLabels(syrax, componentLabels(syrax, name))
+	service.Spec.Selector = componentPodLabels(syrax, name)
+}
+
+// reconcileComponents creates and updates the children of every component of
+// the syrax and deletes those of removed components. A failing component does
+// not hold back the others, its error is reported in its status.
+func (r *SyraxReconciler) reconcileComponents(ctx context.Context, syrax *syraxv2.Syrax) error {
+	var errs []error
+	var statuses []syraxv2.ComponentStatus
+	names := map[string]bool{}
+	for i := range syrax.Spec.Components {
+		component := &syrax.Spec.Components[i]
+		name := componentName(syrax, component)
+		names[name] = true
+
+		status, err := r.reconcileComponent(ctx, syrax, component, name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("component %s: %w", component.Name, err))
+			status.Ready = false
+			status.Message = err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	syrax.Status.Components = statuses
+
+	if err := r.deleteRemovedComponents(ctx, syrax, names); err != nil {
+		errs = append(errs, err)
+	}
+	return stderrors.Join(errs...)
+}
+
+func (r *SyraxReconciler) reconcileComponent(ctx context.Context, syrax *syraxv2.Syrax, component *syraxv2.Component, name string) (syraxv2.ComponentStatus, error) {
+	status := syraxv2.ComponentStatus{Name: component.Name, DeploymentName: name}
+	view := componentView(syrax, component)
+
+	deployment := &appsv1.Deployment{}
+	if err := r.applyComponentChild(ctx, syrax, name, deployment, func() {
+		r.newComponentDeployment(syrax, component, name, deployment)
+	}, func() bool {
+		return ifDeployUpdated(view, deployment)
+	}); err != nil {
+		return status, err
+	}
+	status.AvailableReplicas = deployment.Status.AvailableReplicas
+	status.UpdatedReplicas = deployment.Status.UpdatedReplicas
+
+	var service *corev1.Service
+	if component.ServiceSpec != nil {
+		status.ServiceName = name
+		service = &corev1.Service{}
+		if err := r.applyComponentChild(ctx, syrax, name, service, func() {
+			r.newComponentService(syrax, component, name, service)
+		}, func() bool {
+			desired := &corev1.Service{}
+			r.newComponentService(syrax, component, name, desired)
+			return ifSvcPortsUpdated(view, service) ||
+				service.Spec.Type != view.Spec.ServiceSpec.ServiceType ||
+				!equality.Semantic.DeepEqual(service.Spec.Selector, desired.Spec.Selector) ||
+				!equality.Semantic.DeepEqual(service.OwnerReferences, desired.OwnerReferences)
+		}); err != nil {
+			return status, err
+		}
+	} else if err := r.deleteComponentService(ctx, syrax, name); err != nil {
+		return status, err
+	}
+
+	reason, message := unavailableReason(desiredReplicas(view), deployment, service)
+	status.Ready = reason == ""
+	status.Message = message
+	return status, nil
+}
+
+// deleteComponentService deletes the service of a component whose service
+// was removed from the spec.
+func (r *SyraxReconciler) deleteComponentService(ctx context.Context, syrax *syraxv2.Syrax, name string) error {
+	service := &corev1.Service{}
+	err := r.getChild(ctx, namespcedname.NamespacedName{Namespace: syrax.Namespace, Name: name}, service)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if service.Labels[utils.ComponentLabel] != name || !metav1.IsControlledBy(service, syrax) {
+		return nil
+	}
+	return r.deleteChild(ctx, service)
+}
+
+// applyComponentChild gets the child of a component into obj. A missing child
+// is built into obj with build and created, an existing one is rebuilt and
+// updated when changed reports that it differs from the spec. A child of the
+// same name that does not belong to the component is left alone.
+func (r *SyraxReconciler) applyComponentChild(ctx context.Context, syrax *syraxv2.Syrax, name string, obj client.Object, build func(), changed func() bool) error {
+	kind := r.childKind(obj)
+	err := r.getChild(ctx, namespcedname.NamespacedName{Namespace: syrax.Namespace, Name: name}, obj)
+	if apierrors.IsNotFound(err) {
+		build()
+		if err := r.createChild(ctx, obj); err != nil {
+			r.warningEvent(syrax, ReasonCreateFailed, fmt.Sprintf("unable to create %s %s: %v", strings.ToLower(kind), name, err))
+			return err
+		}
+		r.normalEvent(syrax, ReasonCreated, fmt.Sprintf("created %s %s", strings.ToLower(kind), name))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if owner := metav1.GetControllerOf(obj); obj.GetLabels()[utils.ComponentLabel] != name || (owner != nil && owner.UID != syrax.UID) {
+		namingConflicts.WithLabelValues(kind).Inc()
+		message := fmt.Sprintf("%s %s already exists and does not belong to the syrax", strings.ToLower(kind), name)
+		r.warningEvent(syrax, ReasonNameConflict, message)
+		return stderrors.New(message)
+	}
+	if !changed() {
+		return nil
+	}
+	build()
+	if err := r.applyChildUpdate(ctx, syrax, obj); err != nil {
+		r.warningEvent(syrax, ReasonUpdateFailed, fmt.Sprintf("unable to update %s %s: %v", strings.ToLower(kind), name, err))
+		return err
+	}
+	return nil
+}
+
+// deleteRemovedComponents deletes the children of the components that are no
+// longer in the spec of the syrax.
+func (r *SyraxReconciler) deleteRemovedComponents(ctx context.Context, syrax *syraxv2.Syrax, names map[string]bool) error {
+	selector := client.MatchingLabels(utils.DefaultLabel)
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(syrax.Namespace), selector, client.HasLabels{utils.ComponentLabel}); err != nil {
+		return err
+	}
+	services := &corev1.ServiceList{}
+	if err := r.List(ctx, services, client.InNamespace(syrax.Namespace), selector, client.HasLabels{utils.ComponentLabel}); err != nil {
+		return err
+	}
+
+	var children []client.Object
+	for i := range deployments.Items {
+		children = append(children, &deployments.Items[i])
+	}
+	for i := range services.Items {
+		children = append(children, &services.Items[i])
+	}
+	for _, child := range children {
+		if names[child.GetLabels()[utils.ComponentLabel]] || !metav1.IsControlledBy(child, syrax) {
+			continue
+		}
+		if err := r.deleteChild(ctx, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
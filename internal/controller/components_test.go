@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+)
+
+var _ = Describe("Syrax components", func() {
+	const resourceName = "components-resource"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+	named := func(name string) types.NamespacedName {
+		return types.NamespacedName{Name: name, Namespace: "default"}
+	}
+
+	BeforeEach(func() {
+		resource := newSyrax(typeNamespacedName)
+		resource.Spec.Labels = map[string]string{"app": "shop"}
+		resource.Spec.Env = []corev1.EnvVar{{Name: "DATABASE_URL", Value: "postgres://db"}}
+		resource.Spec.DeploymentSpec.Replicas = ptr.To[int32](2)
+		resource.Spec.DeploymentSpec.Image = "shop/api:1.0"
+		resource.Spec.ServiceSpec.ServiceType = corev1.ServiceTypeClusterIP
+		resource.Spec.Components = []targaryenv2.Component{
+			{
+				Name: "worker",
+				DeploymentSpec: targaryenv2.DeploymentSpec{
+					Replicas: ptr.To[int32](3),
+					Image:    "shop/worker:1.0",
+				},
+			},
+			{
+				Name: "admin",
+				DeploymentSpec: targaryenv2.DeploymentSpec{
+					Image: "shop/admin:1.0",
+				},
+				ServiceSpec: &targaryenv2.ServiceSpec{
+					ServiceType: corev1.ServiceTypeClusterIP,
+					Ports:       []targaryenv2.ServicePort{{Port: 8080}},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+		deleteOwnedChildren(ctx, typeNamespacedName)
+	})
+
+	It("should reconcile every component next to the main workload", func() {
+		controllerReconciler := newReconciler()
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		main := ownedDeployment(ctx, typeNamespacedName)
+		worker := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, named(resourceName+"-worker"), worker)).To(Succeed())
+		admin := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, named(resourceName+"-admin"), admin)).To(Succeed())
+		Expect(*worker.Spec.Replicas).To(Equal(int32(3)))
+		Expect(worker.Spec.Template.Spec.Containers[0].Image).To(Equal("shop/worker:1.0"))
+
+		By("sharing the labels and env of the syrax")
+		for _, deployment := range []*appsv1.Deployment{main, worker, admin} {
+			Expect(deployment.Labels).To(HaveKeyWithValue("app", "shop"))
+			Expect(deployment.Spec.Template.Labels).To(HaveKeyWithValue("app", "shop"))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(ConsistOf(corev1.EnvVar{Name: "DATABASE_URL", Value: "postgres://db"}))
+		}
+
+		By("keeping the pods of every workload apart")
+		mainService := &corev1.Service{}
+		Expect(k8sClient.Get(ctx, named(main.Name), mainService)).To(Succeed())
+		adminService := &corev1.Service{}
+		Expect(k8sClient.Get(ctx, named(resourceName+"-admin"), adminService)).To(Succeed())
+		Expect(labels.SelectorFromSet(mainService.Spec.Selector).Matches(labels.Set(worker.Spec.Template.Labels))).To(BeFalse())
+		Expect(labels.SelectorFromSet(mainService.Spec.Selector).Matches(labels.Set(admin.Spec.Template.Labels))).To(BeFalse())
+		Expect(labels.SelectorFromSet(adminService.Spec.Selector).Matches(labels.Set(main.Spec.Template.Labels))).To(BeFalse())
+		Expect(labels.SelectorFromSet(adminService.Spec.Selector).Matches(labels.Set(admin.Spec.Template.Labels))).To(BeTrue())
+		err := k8sClient.Get(ctx, named(resourceName+"-worker"), &corev1.Service{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(syrax.Status.Components).To(HaveLen(2))
+		Expect(syrax.Status.Components[0].Name).To(Equal("worker"))
+		Expect(syrax.Status.Components[0].DeploymentName).To(Equal(resourceName + "-worker"))
+		Expect(syrax.Status.Components[0].ServiceName).To(BeEmpty())
+		Expect(syrax.Status.Components[0].Ready).To(BeFalse())
+		Expect(syrax.Status.Components[1].ServiceName).To(Equal(resourceName + "-admin"))
+
+		By("reporting the syrax Ready once every component is available")
+		markRolledOut(ctx, main)
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		condition := meta.FindStatusCondition(syrax.Status.Conditions, targaryenv2.ConditionReady)
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("ComponentUnavailable"))
+
+		markRolledOut(ctx, worker)
+		markRolledOut(ctx, admin)
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(syrax.Status.Components[0].Ready).To(BeTrue())
+		Expect(syrax.Status.Components[0].AvailableReplicas).To(Equal(int32(3)))
+		Expect(meta.IsStatusConditionTrue(syrax.Status.Conditions, targaryenv2.ConditionReady)).To(BeTrue())
+
+		By("deleting the children of a removed component")
+		syrax.Spec.Components = syrax.Spec.Components[1:]
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		err = k8sClient.Get(ctx, named(resourceName+"-worker"), &appsv1.Deployment{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(k8sClient.Get(ctx, named(resourceName+"-admin"), &appsv1.Deployment{})).To(Succeed())
+	})
+
+	It("should keep the components out of the name lookup of the main children", func() {
+		controllerReconciler := newReconciler()
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(controllerReconciler.getDeploymentName(syrax)).To(Equal(childBaseName(syrax, "") + "-0"))
+		Expect(controllerReconciler.getServiceName(syrax)).To(Equal(childBaseName(syrax, "") + "-0"))
+
+		worker := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, named(resourceName+"-worker"), worker)).To(Succeed())
+		Expect(worker.Labels).To(HaveKeyWithValue(utils.ComponentLabel, resourceName+"-worker"))
+	})
+})
@@ -0,0 +1,214 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	namespcedname "k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// configVolumeName is the name of the volume of the config files in the pods.
+const configVolumeName = "config-files"
+
+// configMapName returns the name of the ConfigMap of the config files of the
+// syrax, which carries their hash as the ConfigMaps are immutable.
+func configMapName(syrax *syraxv2.Syrax) string {
+	hash := configHash(syrax)
+	if len(hash) > 10 {
+		hash = hash[:10]
+	}
+	return ToLowerCase(syrax.Name) + "config-" + hash
+}
+
+// reconcileConfigFiles writes the config files of the syrax to an immutable
+// ConfigMap named after their hash. The ConfigMaps of the previous files are
+// deleted by deleteStaleConfigMaps once the pods no longer use them.
+func (r *SyraxReconciler) reconcileConfigFiles(ctx context.Context, syrax *syraxv2.Syrax) error {
+	if syrax.Spec.ConfigFiles == nil {
+		return nil
+	}
+	name := configMapName(syrax)
+	configMap := &corev1.ConfigMap{}
+	err := r.getChild(ctx, namespcedname.NamespacedName{Namespace: syrax.Namespace, Name: name}, configMap)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	exists := err == nil
+
+	// setOwner orphans the ConfigMap while the syrax is being deleted.
+	if exists && !metav1.IsControlledBy(configMap, syrax) && syrax.DeletionTimestamp == nil {
+		namingConflicts.WithLabelValues("ConfigMap").Inc()
+		message := fmt.Sprintf("configmap %s already exists and does not belong to the syrax", name)
+		r.warningEvent(syrax, ReasonNameConflict, message)
+		return stderrors.New(message)
+	}
+
+	// only the metadata of an existing ConfigMap may change, its name
+	// guarantees its files.
+	current := configMap.DeepCopy()
+	configMap.Name = name
+	configMap.Namespace = syrax.Namespace
+	configMap.Labels = withTemplateLabels(syrax, syraxLabels(syrax))
+	setOwner(configMap, syrax)
+	if !exists {
+		configMap.Data = syrax.Spec.ConfigFiles.Files
+		configMap.Immutable = ptr.To(true)
+		if err := r.createChild(ctx, configMap); err != nil {
+			return err
+		}
+		r.normalEvent(syrax, ReasonCreated, fmt.Sprintf("created %s %s", strings.ToLower(r.childKind(configMap)), name))
+		return nil
+	}
+	if !equality.Semantic.DeepEqual(current, configMap) {
+		return r.applyChildUpdate(ctx, syrax, configMap)
+	}
+	return nil
+}
+
+// deleteStaleConfigMaps deletes the ConfigMaps of the syrax that hold previous
+// config files, once every deployment of the syrax rolled out and none of
+// their pod templates mounts them anymore. The old pods keep their files
+// until they are replaced.
+func (r *SyraxReconciler) deleteStaleConfigMaps(ctx context.Context, syrax *syraxv2.Syrax) error {
+	selector := client.MatchingLabels(utils.DefaultLabel)
+	configMaps := &corev1.ConfigMapList{}
+	if err := r.List(ctx, configMaps, client.InNamespace(syrax.Namespace), selector); err != nil {
+		return err
+	}
+	current := ""
+	if syrax.Spec.ConfigFiles != nil {
+		current = configMapName(syrax)
+	}
+	var stale []*corev1.ConfigMap
+	for i := range configMaps.Items {
+		configMap := &configMaps.Items[i]
+		if configMap.Name != current && metav1.IsControlledBy(configMap, syrax) {
+			stale = append(stale, configMap)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(syrax.Namespace), selector); err != nil {
+		return err
+	}
+	mounted := map[string]bool{}
+	for i := range deployments.Items {
+		deployment := &deployments.Items[i]
+		if !metav1.IsControlledBy(deployment, syrax) {
+			continue
+		}
+		if !rolloutComplete(deployment) {
+			return nil
+		}
+		for _, volume := range deployment.Spec.Template.Spec.Volumes {
+			if volume.ConfigMap != nil {
+				mounted[volume.ConfigMap.Name] = true
+			}
+		}
+	}
+	for _, configMap := range stale {
+		if mounted[configMap.Name] {
+			continue
+		}
+		if err := r.deleteChild(ctx, configMap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configHash returns the hash of the config files of the syrax, or an empty
+// string when it has none.
+func configHash(syrax *syraxv2.Syrax) string {
+	if syrax.Spec.ConfigFiles == nil {
+		return ""
+	}
+	names := make([]string, 0, len(syrax.Spec.ConfigFiles.Files))
+	for name := range syrax.Spec.ConfigFiles.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hash := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(hash, "%s\x00%s\x00", name, syrax.Spec.ConfigFiles.Files[name])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// configMountPath returns the directory the config files are mounted at.
+func configMountPath(syrax *syraxv2.Syrax) string {
+	if syrax.Spec.ConfigFiles.MountPath != "" {
+		return syrax.Spec.ConfigFiles.MountPath
+	}
+	return utils.DefaultConfigMountPath
+}
+
+// setConfigFiles mounts the ConfigMap of the config files in the pods of the
+// deployment, and stamps their hash on the pod template. Changing the files
+// renames the ConfigMap, which rolls the pods.
+func setConfigFiles(syrax *syraxv2.Syrax, template *corev1.PodTemplateSpec) {
+	if syrax.Spec.ConfigFiles == nil {
+		return
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[utils.ConfigHashAnnotation] = configHash(syrax)
+	template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+		Name: configVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: configMapName(syrax)},
+			},
+		},
+	})
+	for i := range template.Spec.Containers {
+		if template.Spec.Containers[i].Name == utils.ContainerName {
+			template.Spec.Containers[i].VolumeMounts = append(template.Spec.Containers[i].VolumeMounts, corev1.VolumeMount{
+				Name:      configVolumeName,
+				MountPath: configMountPath(syrax),
+				ReadOnly:  true,
+			})
+		}
+	}
+}
+
+// configFilesUpdated reports whether the hash or the mount of the config
+// files on the pod template differ from those of the syrax.
+func configFilesUpdated(syrax *syraxv2.Syrax, deployment *appsv1.Deployment) bool {
+	if deployment.Spec.Template.Annotations[utils.ConfigHashAnnotation] != configHash(syrax) {
+		return true
+	}
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name != utils.ContainerName {
+			continue
+		}
+		mountPath := ""
+		for _, mount := range container.VolumeMounts {
+			if mount.Name == configVolumeName {
+				mountPath = mount.MountPath
+			}
+		}
+		if syrax.Spec.ConfigFiles == nil {
+			return mountPath != ""
+		}
+		return mountPath != configMountPath(syrax)
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+)
+
+var _ = Describe("Syrax config files", func() {
+	const resourceName = "config-resource"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+	// configMapKey returns the key of the ConfigMap of the current files of
+	// the syrax.
+	configMapKey := func() types.NamespacedName {
+		syrax := &targaryenv2.Syrax{}
+		ExpectWithOffset(1, k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		return types.NamespacedName{Name: configMapName(syrax), Namespace: "default"}
+	}
+
+	var controllerReconciler *SyraxReconciler
+
+	// rollOut fakes the rollout of the deployment, twice as recording its
+	// template as the last good one changes its generation again.
+	rollOut := func() {
+		for i := 0; i < 2; i++ {
+			markRolledOut(ctx, ownedDeployment(ctx, typeNamespacedName))
+			reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		}
+	}
+
+	BeforeEach(func() {
+		resource := newSyrax(typeNamespacedName)
+		resource.Spec.ConfigFiles = &targaryenv2.ConfigFilesSpec{
+			Files: map[string]string{
+				"app.yaml":  "port: 8080\n",
+				"LOG_LEVEL": "info",
+			},
+		}
+		resource.Spec.DeploymentSpec.Image = "shop/api:1.0"
+		resource.Spec.ServiceSpec.ServiceType = corev1.ServiceTypeClusterIP
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+		controllerReconciler = newReconciler()
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+		deleteOwnedChildren(ctx, typeNamespacedName)
+	})
+
+	It("should mount the config files and roll the pods when they change", func() {
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		first := configMapKey()
+		Expect(first.Name).To(MatchRegexp(`^config-resource-config-[0-9a-f]{10}$`))
+		configMap := &corev1.ConfigMap{}
+		Expect(k8sClient.Get(ctx, first, configMap)).To(Succeed())
+		Expect(configMap.Data).To(HaveKeyWithValue("app.yaml", "port: 8080\n"))
+		Expect(configMap.Data).To(HaveKeyWithValue("LOG_LEVEL", "info"))
+		Expect(configMap.Immutable).To(HaveValue(BeTrue()))
+		Expect(configMap.OwnerReferences).To(HaveLen(1))
+
+		deployment := ownedDeployment(ctx, typeNamespacedName)
+		template := deployment.Spec.Template
+		Expect(template.Spec.Volumes).To(HaveLen(1))
+		Expect(template.Spec.Volumes[0].ConfigMap.Name).To(Equal(first.Name))
+		Expect(template.Spec.Containers[0].VolumeMounts).To(ConsistOf(corev1.VolumeMount{
+			Name:      "config-files",
+			MountPath: "/etc/config",
+			ReadOnly:  true,
+		}))
+		hash := template.Annotations[utils.ConfigHashAnnotation]
+		Expect(hash).NotTo(BeEmpty())
+
+		By("mounting a new configmap with the changed files")
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Spec.ConfigFiles.Files["LOG_LEVEL"] = "debug"
+		syrax.Spec.ConfigFiles.MountPath = "/config"
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		second := configMapKey()
+		Expect(second).NotTo(Equal(first))
+		Expect(k8sClient.Get(ctx, second, configMap)).To(Succeed())
+		Expect(configMap.Data).To(HaveKeyWithValue("LOG_LEVEL", "debug"))
+		deployment = ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Annotations[utils.ConfigHashAnnotation]).NotTo(Equal(hash))
+		Expect(deployment.Spec.Template.Spec.Volumes[0].ConfigMap.Name).To(Equal(second.Name))
+		Expect(deployment.Spec.Template.Spec.Containers[0].VolumeMounts[0].MountPath).To(Equal("/config"))
+
+		By("keeping the previous configmap until the rollout completes")
+		Expect(k8sClient.Get(ctx, first, configMap)).To(Succeed())
+		rollOut()
+		err := k8sClient.Get(ctx, first, configMap)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		By("deleting the configmap and the mount with the files")
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Spec.ConfigFiles = nil
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		deployment = ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Annotations).NotTo(HaveKey(utils.ConfigHashAnnotation))
+		Expect(deployment.Spec.Template.Spec.Volumes).To(BeEmpty())
+		Expect(k8sClient.Get(ctx, second, configMap)).To(Succeed())
+		rollOut()
+		err = k8sClient.Get(ctx, second, configMap)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should hash the files independently of their order", func() {
+		syrax := &targaryenv2.Syrax{Spec: targaryenv2.SyraxSpec{ConfigFiles: &targaryenv2.ConfigFilesSpec{
+			Files: map[string]string{"a": "1", "b": "2"},
+		}}}
+		hash := configHash(syrax)
+		syrax.Spec.ConfigFiles.Files = map[string]string{"b": "2", "a": "1"}
+		Expect(configHash(syrax)).To(Equal(hash))
+		syrax.Spec.ConfigFiles.Files = map[string]string{"a": "12"}
+		Expect(configHash(syrax)).NotTo(Equal(hash))
+	})
+})
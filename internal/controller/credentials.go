@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	namespcedname "k8s.io/apimachinery/pkg/types"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// registryCredentialsKey indexes the syraxes by the namespace/name of the
+// secret their registry credentials are copied from.
+const registryCredentialsKey = ".spec.registryCredentialsRef"
+
+// registryCredentialsName returns the name of the copy of the registry
+// credentials of the syrax.
+func registryCredentialsName(syrax *syraxv2.Syrax) string {
+	return ToLowerCase(syrax.Name) + "registry-credentials"
+}
+
+// reconcileRegistryCredentials copies the secret named by the
+// registryCredentialsRef of the syrax into its namespace, and deletes the
+// copy once the syrax no longer refers to a secret. Only the namespaces of
+// RegistryCredentialsNamespaces may be copied from, whose secrets the manager
+// caches.
+func (r *SyraxReconciler) reconcileRegistryCredentials(ctx context.Context, syrax *syraxv2.Syrax) error {
+	name := registryCredentialsName(syrax)
+	secret := &corev1.Secret{}
+	err := r.getChild(ctx, namespcedname.NamespacedName{Namespace: syrax.Namespace, Name: name}, secret)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	exists := err == nil
+
+	ref := syrax.Spec.RegistryCredentialsRef
+	if ref == nil {
+		if exists && metav1.IsControlledBy(secret, syrax) {
+			return r.deleteChild(ctx, secret)
+		}
+		return nil
+	}
+	if syrax.DeletionTimestamp != nil {
+		// the copy is kept like the other children, without waiting for a
+		// source that may be gone by now.
+		if exists && metav1.IsControlledBy(secret, syrax) {
+			setOwner(secret, syrax)
+			return r.updateChild(ctx, secret)
+		}
+		return nil
+	}
+	if exists && !metav1.IsControlledBy(secret, syrax) {
+		namingConflicts.WithLabelValues("Secret").Inc()
+		message := fmt.Sprintf("secret %s already exists and does not belong to the syrax", name)
+		r.warningEvent(syrax, ReasonNameConflict, message)
+		return stderrors.New(message)
+	}
+	if !slices.Contains(r.RegistryCredentialsNamespaces, ref.Namespace) {
+		return apierrors.NewForbidden(corev1.Resource("secrets"), ref.Name,
+			fmt.Errorf("registry credentials may not be copied from namespace %s", ref.Namespace))
+	}
+	source := &corev1.Secret{}
+	if err := r.Get(ctx, namespcedname.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, source); err != nil {
+		if apierrors.IsNotFound(err) {
+			return &missingReferenceError{err: fmt.Errorf("registry credentials secret %s/%s not found", ref.Namespace, ref.Name)}
+		}
+		return err
+	}
+	if source.Type != corev1.SecretTypeDockerConfigJson && source.Type != corev1.SecretTypeDockercfg {
+		return &missingReferenceError{err: fmt.Errorf("registry credentials secret %s/%s is of type %s, not %s",
+			ref.Namespace, ref.Name, source.Type, corev1.SecretTypeDockerConfigJson)}
+	}
+
+	if exists && secret.Type != source.Type {
+		// the type of a secret is immutable.
+		if err := r.deleteChild(ctx, secret); err != nil {
+			return err
+		}
+		secret, exists = &corev1.Secret{}, false
+	}
+	current := secret.DeepCopy()
+	secret.Name = name
+	secret.Namespace = syrax.Namespace
+	secret.Labels = withTemplateLabels(syrax, syraxLabels(syrax))
+	secret.Type = source.Type
+	secret.Data = source.Data
+	setOwner(secret, syrax)
+
+	kind := strings.ToLower(r.childKind(secret))
+	switch {
+	case !exists:
+		if err := r.createChild(ctx, secret); err != nil {
+			return err
+		}
+		r.normalEvent(syrax, ReasonCreated, fmt.Sprintf("created %s %s from %s/%s", kind, name, ref.Namespace, ref.Name))
+	case !equality.Semantic.DeepEqual(current, secret):
+		if err := r.updateChild(ctx, secret); err != nil {
+			return err
+		}
+		r.normalEvent(syrax, ReasonUpdated, fmt.Sprintf("synced %s %s with %s/%s", kind, name, ref.Namespace, ref.Name))
+	}
+	return nil
+}
+
+// imagePullSecrets returns the pull secrets of the deployment of the syrax,
+// followed by the copy of its registry credentials.
+func imagePullSecrets(syrax *syraxv2.Syrax) []corev1.LocalObjectReference {
+	secrets := append([]corev1.LocalObjectReference(nil), syrax.Spec.DeploymentSpec.ImagePullSecrets...)
+	if syrax.Spec.RegistryCredentialsRef != nil {
+		secrets = append(secrets, corev1.LocalObjectReference{Name: registryCredentialsName(syrax)})
+	}
+	return secrets
+}
+
+// imagePullPolicy returns the pull policy of the deployment of the syrax,
+// defaulted like the API server does: Always for images without a tag or with
+// the latest tag, IfNotPresent otherwise.
+func imagePullPolicy(syrax *syraxv2.Syrax) corev1.PullPolicy {
+	if policy := syrax.Spec.DeploymentSpec.ImagePullPolicy; policy != "" {
+		return policy
+	}
+	image := syrax.Spec.DeploymentSpec.Image
+	if strings.Contains(image, "@") {
+		return corev1.PullIfNotPresent
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") && image[i+1:] != "latest" {
+		return corev1.PullIfNotPresent
+	}
+	return corev1.PullAlways
+}
+
+// imagePullUpdated reports whether the pull policy or the pull secrets of the
+// deployment differ from those of the syrax.
+func imagePullUpdated(syrax *syraxv2.Syrax, deployment *appsv1.Deployment) bool {
+	secrets := imagePullSecrets(syrax)
+	current := deployment.Spec.Template.Spec.ImagePullSecrets
+	if len(secrets) != len(current) || (len(secrets) > 0 && !equality.Semantic.DeepEqual(secrets, current)) {
+		return true
+	}
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name == utils.ContainerName {
+			return container.ImagePullPolicy != imagePullPolicy(syrax)
+		}
+	}
+	return false
+}
+
+// syraxesForRegistryCredentials returns a request for every syrax copying the
+// secret, so that changes of the source reach the copies right away.
+func (r *SyraxReconciler) syraxesForRegistryCredentials(ctx context.Context, secret client.Object) []reconcile.Request {
+	syraxes := &syraxv2.SyraxList{}
+	key := secret.GetNamespace() + "/" + secret.GetName()
+	if err := r.List(ctx, syraxes, client.MatchingFields{registryCredentialsKey: key}); err != nil {
+		log.FromContext(ctx).Error(err, "Unable to list the syraxes of registry credentials", "secret", key)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(syraxes.Items))
+	for i := range syraxes.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&syraxes.Items[i])})
+	}
+	return requests
+}
@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+)
+
+var _ = Describe("Syrax registry credentials", func() {
+	const resourceName = "credentials-resource"
+	const centralNamespace = "registry-credentials"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+	copyName := types.NamespacedName{
+		Name:      resourceName + "-registry-credentials",
+		Namespace: "default",
+	}
+
+	var controllerReconciler *SyraxReconciler
+	var source *corev1.Secret
+
+	BeforeEach(func() {
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: centralNamespace}}
+		Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, namespace))).To(Succeed())
+
+		source = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "pull", Namespace: centralNamespace},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"registry.example.com":{}}}`)},
+		}
+		Expect(k8sClient.Create(ctx, source)).To(Succeed())
+
+		resource := newSyrax(typeNamespacedName)
+		resource.Spec.RegistryCredentialsRef = &targaryenv2.RegistryCredentialsReference{
+			Namespace: centralNamespace,
+			Name:      source.Name,
+		}
+		resource.Spec.DeploymentSpec.Image = "registry.example.com/shop/api:1.0"
+		resource.Spec.DeploymentSpec.ImagePullPolicy = corev1.PullAlways
+		resource.Spec.DeploymentSpec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "team-pull"}}
+		resource.Spec.ServiceSpec.ServiceType = corev1.ServiceTypeClusterIP
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+		controllerReconciler = newReconciler()
+		controllerReconciler.RegistryCredentialsNamespaces = []string{centralNamespace}
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, source))).To(Succeed())
+		deleteOwnedChildren(ctx, typeNamespacedName)
+	})
+
+	It("should copy the credentials and pull the images with them", func() {
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		secret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, copyName, secret)).To(Succeed())
+		Expect(secret.Type).To(Equal(corev1.SecretTypeDockerConfigJson))
+		Expect(secret.Data).To(Equal(source.Data))
+		Expect(secret.OwnerReferences).To(HaveLen(1))
+
+		deployment := ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Spec.ImagePullSecrets).To(Equal([]corev1.LocalObjectReference{
+			{Name: "team-pull"},
+			{Name: copyName.Name},
+		}))
+		Expect(deployment.Spec.Template.Spec.Containers[0].ImagePullPolicy).To(Equal(corev1.PullAlways))
+
+		By("keeping the copy in sync with the source")
+		source.Data[corev1.DockerConfigJsonKey] = []byte(`{"auths":{"registry.example.com":{"auth":"dXNlcjpwYXNz"}}}`)
+		Expect(k8sClient.Update(ctx, source)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(k8sClient.Get(ctx, copyName, secret)).To(Succeed())
+		Expect(secret.Data).To(Equal(source.Data))
+
+		By("deleting the copy once the syrax no longer refers to the source")
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Spec.RegistryCredentialsRef = nil
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		err := k8sClient.Get(ctx, copyName, secret)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		deployment = ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Spec.ImagePullSecrets).To(Equal([]corev1.LocalObjectReference{{Name: "team-pull"}}))
+	})
+
+	It("should report a missing source secret", func() {
+		Expect(k8sClient.Delete(ctx, source)).To(Succeed())
+
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).To(HaveOccurred())
+
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		condition := meta.FindStatusCondition(syrax.Status.Conditions, targaryenv2.ConditionDegraded)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal(string(errorMissingReference)))
+	})
+
+	It("should not copy the credentials from a namespace that is not allowed", func() {
+		controllerReconciler.RegistryCredentialsNamespaces = []string{"other"}
+
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).To(HaveOccurred())
+
+		err = k8sClient.Get(ctx, copyName, &corev1.Secret{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		condition := meta.FindStatusCondition(syrax.Status.Conditions, targaryenv2.ConditionDegraded)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal(string(errorForbidden)))
+	})
+
+	It("should let a syrax go once its source secret is gone", func() {
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Spec.DeletionPolicy = targaryenv2.DeletionPolicyDelete
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		Expect(k8sClient.Delete(ctx, source)).To(Succeed())
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		err := k8sClient.Get(ctx, typeNamespacedName, syrax)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		secret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, copyName, secret)).To(Succeed())
+		Expect(secret.OwnerReferences).To(BeEmpty())
+		Expect(k8sClient.Delete(ctx, secret)).To(Succeed())
+	})
+
+	It("should default the pull policy like the API server", func() {
+		syrax := &targaryenv2.Syrax{}
+		for image, policy := range map[string]corev1.PullPolicy{
+			"nginx":                       corev1.PullAlways,
+			"nginx:latest":                corev1.PullAlways,
+			"nginx:1.25":                  corev1.PullIfNotPresent,
+			"registry:5000/app":           corev1.PullAlways,
+			"registry:5000/app@sha256:ab": corev1.PullIfNotPresent,
+		} {
+			syrax.Spec.DeploymentSpec.Image = image
+			Expect(imagePullPolicy(syrax)).To(Equal(policy), image)
+		}
+	})
+})
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// errorClass tells how the reconciler responds to an error.
+type errorClass string
+
+const (
+	// errorNotFound is returned when an object vanished during the reconcile.
+	errorNotFound errorClass = "NotFound"
+	// errorConflict is returned when an object changed during the reconcile.
+	errorConflict errorClass = "Conflict"
+	// errorForbidden is returned when the controller lacks a permission or may
+	// not use a reference.
+	errorForbidden errorClass = "Forbidden"
+	// errorInvalid is returned when the API server rejects an object.
+	errorInvalid errorClass = "Invalid"
+	// errorMissingReference is returned when an object the syrax refers to,
+	// such as its template, does not exist.
+	errorMissingReference errorClass = "MissingReference"
+	// errorTransient covers every other error, such as timeouts and
+	// unavailable API servers.
+	errorTransient errorClass = "Transient"
+)
+
+// missingReferenceError tells that an object the syrax refers to does not
+// exist. Unlike a child that vanished, it does not come back by requeueing.
+type missingReferenceError struct {
+	err error
+}
+
+func (e *missingReferenceError) Error() string { return e.err.Error() }
+
+func (e *missingReferenceError) Unwrap() error { return e.err }
+
+func classifyError(err error) errorClass {
+	var missing *missingReferenceError
+	switch {
+	case errors.As(err, &missing):
+		return errorMissingReference
+	case apierrors.IsNotFound(err):
+		return errorNotFound
+	case apierrors.IsConflict(err), apierrors.IsAlreadyExists(err):
+		return errorConflict
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return errorForbidden
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		return errorInvalid
+	}
+	return errorTransient
+}
+
+// terminal reports whether retrying cannot fix errors of the class.
+func (c errorClass) terminal() bool {
+	return c == errorForbidden || c == errorInvalid || c == errorMissingReference
+}
+
+// handleError turns an error of the reconcile into its result:
+//   - not-found and conflict errors requeue the syrax right away, as they only
+//     mean that the cache is behind the API server;
+//   - transient errors are returned, so that the syrax is retried with the
+//     exponential backoff of the rate limiter;
+//   - terminal errors set the Degraded condition and are not retried until
+//     the syrax, one of its children or an object it refers to changes.
+func (r *SyraxReconciler) handleError(ctx context.Context, syrax *syraxv2.Syrax, err error) (ctrl.Result, error) {
+	if err == nil {
+		return ctrl.Result{}, nil
+	}
+	class := classifyError(err)
+	logger := log.FromContext(ctx).WithValues("errorClass", class)
+	switch {
+	case class == errorNotFound || class == errorConflict:
+		logger.V(1).Info("Requeueing after a stale read", "error", err.Error())
+		return ctrl.Result{Requeue: true}, nil
+	case !class.terminal():
+		return ctrl.Result{}, err
+	}
+
+	logger.Error(err, "Reconcile failed with a terminal error")
+	if syrax != nil {
+		if syrax.Status.AvailableReplicas == nil {
+			// the schema requires it, a syrax may fail before its first status.
+			syrax.Status.AvailableReplicas = new(int32)
+		}
+		meta.SetStatusCondition(&syrax.Status.Conditions, metav1.Condition{
+			Type:               syraxv2.ConditionDegraded,
+			Status:             metav1.ConditionTrue,
+			Reason:             string(class),
+			Message:            err.Error(),
+			ObservedGeneration: syrax.Generation,
+		})
+		if statusErr := r.Status().Update(ctx, syrax); statusErr != nil {
+			return ctrl.Result{}, statusErr
+		}
+	}
+	return ctrl.Result{}, reconcile.TerminalError(err)
+}
+
+// NewRateLimiter returns the rate limiter of the default controller with a
+// configurable per-syrax exponential backoff.
+func NewRateLimiter(baseDelay, maxDelay time.Duration) ratelimiter.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(baseDelay, maxDelay),
+		// the overall rate limit of workqueue.DefaultControllerRateLimiter.
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
+}
+
+// setNotDegraded clears the Degraded condition after a successful reconcile.
+func setNotDegraded(syrax *syraxv2.Syrax) {
+	if meta.FindStatusCondition(syrax.Status.Conditions, syraxv2.ConditionDegraded) == nil {
+		return
+	}
+	meta.SetStatusCondition(&syrax.Status.Conditions, metav1.Condition{
+		Type:               syraxv2.ConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Reconciled",
+		Message:            "the children of the syrax are reconciled",
+		ObservedGeneration: syrax.Generation,
+	})
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+)
+
+var _ = Describe("Reconcile errors", func() {
+	const resourceName = "errors-resource"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+
+	BeforeEach(func() {
+		resource := newSyrax(typeNamespacedName)
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+	})
+
+	It("should classify API errors", func() {
+		Expect(classifyError(apierrors.NewNotFound(deployments, "a"))).To(Equal(errorNotFound))
+		Expect(classifyError(apierrors.NewConflict(deployments, "a", errors.New("changed")))).To(Equal(errorConflict))
+		Expect(classifyError(apierrors.NewForbidden(deployments, "a", errors.New("denied")))).To(Equal(errorForbidden))
+		Expect(classifyError(apierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "Deployment"}, "a", field.ErrorList{}))).To(Equal(errorInvalid))
+		Expect(classifyError(apierrors.NewServiceUnavailable("down"))).To(Equal(errorTransient))
+		Expect(classifyError(errors.New("connection reset"))).To(Equal(errorTransient))
+		Expect(classifyError(&missingReferenceError{err: apierrors.NewNotFound(deployments, "a")})).To(Equal(errorMissingReference))
+	})
+
+	It("should requeue conflicts, retry transient errors and report terminal ones", func() {
+		controllerReconciler := newReconciler()
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+
+		result, err := controllerReconciler.handleError(ctx, syrax, apierrors.NewConflict(deployments, "a", errors.New("changed")))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Requeue).To(BeTrue())
+
+		transient := apierrors.NewServiceUnavailable("down")
+		_, err = controllerReconciler.handleError(ctx, syrax, transient)
+		Expect(err).To(Equal(transient))
+
+		_, err = controllerReconciler.handleError(ctx, syrax, apierrors.NewForbidden(deployments, "a", errors.New("denied")))
+		Expect(errors.Is(err, reconcile.TerminalError(nil))).To(BeTrue())
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		condition := meta.FindStatusCondition(syrax.Status.Conditions, targaryenv2.ConditionDegraded)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(string(errorForbidden)))
+
+		By("Reconciling successfully")
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(meta.IsStatusConditionFalse(syrax.Status.Conditions, targaryenv2.ConditionDegraded)).To(BeTrue())
+	})
+})
@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Reasons of the events emitted for the children of a syrax and for the
+// syraxes of a set.
+const (
+	ReasonCreated            = "Created"
+	ReasonUpdated            = "Updated"
+	ReasonDriftCorrected     = "DriftCorrected"
+	ReasonCreateFailed       = "CreateFailed"
+	ReasonUpdateFailed       = "UpdateFailed"
+	ReasonNameConflict       = "NameConflict"
+	ReasonCleanupComplete    = "CleanupComplete"
+	ReasonRenaming           = "Renaming"
+	ReasonRenamed            = "Renamed"
+	ReasonTemplateFailed     = "TemplateFailed"
+	ReasonCredentialsFailed  = "CredentialsFailed"
+	ReasonImageResolved      = "ImageResolved"
+	ReasonImageResolveFailed = "ImageResolveFailed"
+	ReasonRolledBack         = "RolledBack"
+	ReasonRollbackFailed     = "RollbackFailed"
+	ReasonCanaryPromoted     = "CanaryPromoted"
+	ReasonCanaryAborted      = "CanaryAborted"
+	ReasonBlueGreenPromoted  = "BlueGreenPromoted"
+	ReasonGenerateFailed     = "GenerateFailed"
+	ReasonDeleted            = "Deleted"
+)
+
+const (
+	// eventDedupWindow is how long an identical warning of an object is
+	// suppressed.
+	eventDedupWindow = 10 * time.Minute
+	// eventBurst and eventQPS limit the events of a single object.
+	eventBurst = 10
+	eventQPS   = 0.1
+)
+
+type eventKey struct {
+	object    types.NamespacedName
+	eventtype string
+	reason    string
+	message   string
+}
+
+// eventLimiter drops warnings that were already emitted recently for the same
+// object, as a failing reconcile repeats them on every retry, and rate limits
+// the rest per object so that a hot reconcile loop cannot flood the namespace.
+// Normal events mark transitions and are not deduplicated, so that a change
+// made twice is reported twice. There is one limiter per kind of object.
+type eventLimiter struct {
+	mu       sync.Mutex
+	emitted  map[eventKey]time.Time
+	limiters map[types.NamespacedName]flowcontrol.PassiveRateLimiter
+}
+
+var (
+	events    = newEventLimiter()
+	setEvents = newEventLimiter()
+)
+
+func newEventLimiter() *eventLimiter {
+	return &eventLimiter{
+		emitted:  map[eventKey]time.Time{},
+		limiters: map[types.NamespacedName]flowcontrol.PassiveRateLimiter{},
+	}
+}
+
+func (l *eventLimiter) allow(key eventKey, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	dedup := key.eventtype == corev1.EventTypeWarning
+	if last, ok := l.emitted[key]; dedup && ok && now.Sub(last) < eventDedupWindow {
+		return false
+	}
+	limiter, ok := l.limiters[key.object]
+	if !ok {
+		limiter = flowcontrol.NewTokenBucketPassiveRateLimiter(eventQPS, eventBurst)
+		l.limiters[key.object] = limiter
+	}
+	if !limiter.TryAccept() {
+		return false
+	}
+	for k, last := range l.emitted {
+		if now.Sub(last) >= eventDedupWindow {
+			delete(l.emitted, k)
+		}
+	}
+	if dedup {
+		l.emitted[key] = now
+	}
+	return true
+}
+
+// forget drops the state kept for an object that no longer exists.
+func (l *eventLimiter) forget(key types.NamespacedName) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.limiters, key)
+	for k := range l.emitted {
+		if k.object == key {
+			delete(l.emitted, k)
+		}
+	}
+}
+
+// record records an event on the object unless it is a duplicate of a recent
+// warning or the object exceeded its event rate.
+func (l *eventLimiter) record(recorder record.EventRecorder, object client.Object, eventtype, reason, message string) {
+	key := eventKey{
+		object:    client.ObjectKeyFromObject(object),
+		eventtype: eventtype,
+		reason:    reason,
+		message:   message,
+	}
+	if l.allow(key, time.Now()) {
+		recorder.Event(object, eventtype, reason, message)
+	}
+}
+
+// event records an event on the syrax through the limiter of the syraxes.
+func (r *SyraxReconciler) event(syrax *syraxv2.Syrax, eventtype, reason, message string) {
+	events.record(r.Recorder, syrax, eventtype, reason, message)
+}
+
+// normalEvent and warningEvent are shorthands for event.
+func (r *SyraxReconciler) normalEvent(syrax *syraxv2.Syrax, reason, message string) {
+	r.event(syrax, corev1.EventTypeNormal, reason, message)
+}
+
+func (r *SyraxReconciler) warningEvent(syrax *syraxv2.Syrax, reason, message string) {
+	r.event(syrax, corev1.EventTypeWarning, reason, message)
+}
+
+// normalEvent and warningEvent record an event on the set through the limiter
+// of the sets.
+func (r *SyraxSetReconciler) normalEvent(set *syraxv2.SyraxSet, reason, message string) {
+	setEvents.record(r.Recorder, set, corev1.EventTypeNormal, reason, message)
+}
+
+func (r *SyraxSetReconciler) warningEvent(set *syraxv2.SyraxSet, reason, message string) {
+	setEvents.record(r.Recorder, set, corev1.EventTypeWarning, reason, message)
+}
+
+// applyChildUpdate updates a child whose spec differs from the desired one, and
+// records whether it followed a change of the syrax or corrected a drift.
+func (r *SyraxReconciler) applyChildUpdate(ctx context.Context, syrax *syraxv2.Syrax, obj client.Object) error {
+	kind := strings.ToLower(r.childKind(obj))
+	if specChanged(syrax) {
+		if err := r.updateChild(ctx, obj); err != nil {
+			return err
+		}
+		r.normalEvent(syrax, ReasonUpdated, fmt.Sprintf("updated %s %s to the syrax spec", kind, obj.GetName()))
+		return nil
+	}
+	if err := r.correctDrift(ctx, obj); err != nil {
+		return err
+	}
+	r.normalEvent(syrax, ReasonDriftCorrected, fmt.Sprintf("reverted manual changes to %s %s", kind, obj.GetName()))
+	return nil
+}
+
+// specChanged reports whether the spec of the syrax changed since its status
+// was last written, which tells an update of the children apart from the
+// correction of a drift.
+func specChanged(syrax *syraxv2.Syrax) bool {
+	progressing := meta.FindStatusCondition(syrax.Status.Conditions, syraxv2.ConditionProgressing)
+	return progressing == nil || progressing.ObservedGeneration != syrax.Generation
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("Syrax events", func() {
+	const resourceName = "events-resource"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+
+	BeforeEach(func() {
+		resource := newSyrax(typeNamespacedName)
+		resource.Spec.DeploymentSpec.Replicas = ptr.To[int32](2)
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+		events.forget(typeNamespacedName)
+	})
+
+	It("should only emit events on transitions", func() {
+		recorder := record.NewFakeRecorder(100)
+		controllerReconciler := newReconciler()
+		controllerReconciler.Recorder = recorder
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal " + ReasonCreated + " created deployment")))
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal " + ReasonCreated + " created service")))
+
+		By("Reconciling again without changes")
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(recorder.Events).NotTo(Receive())
+
+		By("Scaling the deployment by hand")
+		deployment := ownedDeployment(ctx, typeNamespacedName)
+		deployment.Spec.Replicas = ptr.To[int32](5)
+		Expect(k8sClient.Update(ctx, deployment)).To(Succeed())
+
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal " + ReasonDriftCorrected)))
+	})
+
+	It("should drop duplicate warnings and rate limit the rest", func() {
+		limiter := newEventLimiter()
+		now := time.Now()
+		warning := eventKey{object: typeNamespacedName, eventtype: "Warning", reason: ReasonUpdateFailed, message: "failed"}
+		Expect(limiter.allow(warning, now)).To(BeTrue())
+		Expect(limiter.allow(warning, now)).To(BeFalse())
+		Expect(limiter.allow(warning, now.Add(eventDedupWindow))).To(BeTrue())
+
+		By("Repeating a transition")
+		key := eventKey{object: typeNamespacedName, eventtype: "Normal", reason: ReasonUpdated, message: "updated"}
+		Expect(limiter.allow(key, now)).To(BeTrue())
+		Expect(limiter.allow(key, now)).To(BeTrue())
+
+		allowed := 0
+		for i := 0; i < 2*eventBurst; i++ {
+			key.message = fmt.Sprintf("message %d", i)
+			if limiter.allow(key, now) {
+				allowed++
+			}
+		}
+		Expect(allowed).To(Equal(eventBurst - 4))
+	})
+})
@@ -1,71 +1,172 @@
 package controller
 
 import (
-	"bytes"
 	"context"
 	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	namespcedname "k8s.io/apimachinery/pkg/types"
-	syraxv1 "resource.controller.sigs/resource-controller-k8s-sigs/api/v1"
+	"k8s.io/utils/ptr"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
 )
 
-func (r *SyraxReconciler) newDeployment(syrax *syraxv1.Syrax, name string, deployment *appsv1.Deployment) {
-
+// syraxLabels returns the labels put on every child of the syrax and in the
+// selectors of their pods.
+func syraxLabels(syrax *syraxv2.Syrax) map[string]string {
 	labels := make(map[string]string)
 	for k, v := range syrax.Spec.Labels {
 		labels[k] = v
 	}
 	labels["dracarys"] = "im-now-the-servant-of-the-white-walkers"
+	return labels
+}
+
+// withTemplateLabels returns the labels with the labels of the template of
+// the syrax under them. They are only put on the metadata of the children and
+// of their pods, never in the immutable selectors.
+func withTemplateLabels(syrax *syraxv2.Syrax, labels map[string]string) map[string]string {
+	merged := make(map[string]string)
+	if syrax.Status.Template != nil {
+		for k, v := range syrax.Status.Template.Labels {
+			merged[k] = v
+		}
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	return merged
+}
+
+// templateLabelsUpdated reports whether the pods miss a label of the template
+// of the syrax or carry an outdated value of it.
+func templateLabelsUpdated(syrax *syraxv2.Syrax, labels map[string]string) bool {
+	for k, v := range withTemplateLabels(syrax, syraxLabels(syrax)) {
+		if labels[k] != v {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceSelector returns the pod selector of the main service. Once a
+// blue-green rollout is set up, it only selects the pods of the active color.
+func serviceSelector(syrax *syraxv2.Syrax) map[string]string {
+	selector := syraxLabels(syrax)
+	if blueGreenEnabled(syrax) && syrax.Status.BlueGreen != nil {
+		selector[utils.ColorLabel] = syrax.Status.BlueGreen.ActiveColor
+	}
+	return selector
+}
+
+func (r *SyraxReconciler) newDeployment(syrax *syraxv2.Syrax, name string, deployment *appsv1.Deployment) {
+
+	labels := syraxLabels(syrax)
 
 	deployment.Name = name
 
-	deployment.Spec.Replicas = syrax.Spec.DeploymentSpec.Replicas
+	deployment.Spec.Replicas = nil
+	if syrax.Spec.DeploymentSpec.Replicas != nil {
+		deployment.Spec.Replicas = ptr.To(*syrax.Spec.DeploymentSpec.Replicas)
+	}
+	deployment.Spec.Strategy = deploymentStrategy(syrax)
+	deployment.Spec.MinReadySeconds = syrax.Spec.DeploymentSpec.MinReadySeconds
+	if syrax.Spec.DeploymentSpec.ProgressDeadlineSeconds != nil {
+		deployment.Spec.ProgressDeadlineSeconds = syrax.Spec.DeploymentSpec.ProgressDeadlineSeconds
+	}
 
 	setOwner(deployment, syrax)
 
 	if syrax.ObjectMeta.Namespace != "" {
 		deployment.Namespace = syrax.ObjectMeta.Namespace
 	}
-	deployment.Labels = labels
+	deployment.Labels = withTemplateLabels(syrax, labels)
 
 	deploymentImage := syrax.Spec.DeploymentSpec.Image
 
 	containerPorts := []corev1.ContainerPort{}
-
-	if syrax.Spec.ServiceSpec.TargetPort != nil {
-		containerPorts[0].ContainerPort = *syrax.Spec.ServiceSpec.TargetPort
+	for _, port := range syrax.Spec.ServiceSpec.Ports {
+		if port.TargetPort != nil {
+			containerPorts = append(containerPorts, corev1.ContainerPort{ContainerPort: *port.TargetPort})
+		}
 	}
 	deployment.Spec.Selector = &metav1.LabelSelector{
 		MatchLabels: labels,
 	}
 	deployment.Spec.Template = corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
-			Labels: labels,
+			Labels: withTemplateLabels(syrax, labels),
 		},
 		Spec: corev1.PodSpec{
 			Containers: []corev1.Container{
 				{
-					Name:    utils.ContainerName,
-					Image:   deploymentImage,
-					Command: syrax.Spec.DeploymentSpec.Commands,
-					Ports:   containerPorts,
+					Name:            utils.ContainerName,
+					Image:           deploymentImage,
+					ImagePullPolicy: imagePullPolicy(syrax),
+					Command:         syrax.Spec.DeploymentSpec.Command,
+					Args:            syrax.Spec.DeploymentSpec.Args,
+					Env:             syrax.Spec.Env,
+					Ports:           containerPorts,
+					Resources:       containerResources(syrax),
+					LivenessProbe:   defaultedProbe(syrax.Spec.DeploymentSpec.LivenessProbe),
+					ReadinessProbe:  defaultedProbe(syrax.Spec.DeploymentSpec.ReadinessProbe),
 				},
 			},
+			ImagePullSecrets: imagePullSecrets(syrax),
 		},
 	}
+	setConfigFiles(syrax, &deployment.Spec.Template)
+	setRestart(syrax, &deployment.Spec.Template)
 }
 
-func (r *SyraxReconciler) newService(syrax *syraxv1.Syrax, name string, service *corev1.Service) *corev1.Service {
-	labels := make(map[string]string)
-	for k, v := range syrax.Spec.Labels {
-		labels[k] = v
+func containerResources(syrax *syraxv2.Syrax) corev1.ResourceRequirements {
+	if syrax.Spec.DeploymentSpec.Resources == nil {
+		return corev1.ResourceRequirements{}
 	}
-	labels["dracarys"] = "im-now-the-servant-of-the-white-walkers"
+	return *syrax.Spec.DeploymentSpec.Resources.DeepCopy()
+}
+
+// defaultedProbe returns a copy of the probe with the defaults of the API
+// server, so that it compares equal to the probe read back from the
+// deployment.
+func defaultedProbe(probe *corev1.Probe) *corev1.Probe {
+	if probe == nil {
+		return nil
+	}
+	probe = probe.DeepCopy()
+	if probe.TimeoutSeconds == 0 {
+		probe.TimeoutSeconds = 1
+	}
+	if probe.PeriodSeconds == 0 {
+		probe.PeriodSeconds = 10
+	}
+	if probe.SuccessThreshold == 0 {
+		probe.SuccessThreshold = 1
+	}
+	if probe.FailureThreshold == 0 {
+		probe.FailureThreshold = 3
+	}
+	return probe
+}
+
+// deploymentStrategy returns the strategy requested by the syrax, falling
+// back to a RollingUpdate with the API server defaults.
+func deploymentStrategy(syrax *syraxv2.Syrax) appsv1.DeploymentStrategy {
+	if syrax.Spec.DeploymentSpec.Strategy == nil {
+		return appsv1.DeploymentStrategy{Type: appsv1.RollingUpdateDeploymentStrategyType}
+	}
+	return *syrax.Spec.DeploymentSpec.Strategy.DeepCopy()
+}
+
+func (r *SyraxReconciler) newService(syrax *syraxv2.Syrax, name string, service *corev1.Service) *corev1.Service {
+	labels := syraxLabels(syrax)
 
 	service.Name = name
 	serviceType := syrax.Spec.ServiceSpec.ServiceType
@@ -75,99 +176,142 @@ func (r *SyraxReconciler) newService(syrax *syraxv1.Syrax, name string, service
 	}
 	service.Spec.Type = corev1.ServiceType(serviceType)
 
-	servicePort := syrax.Spec.ServiceSpec.Port
-	ports := []corev1.ServicePort{
-		{
-			Port: *servicePort,
-		},
+	ports := []corev1.ServicePort{}
+	for _, servicePort := range syrax.Spec.ServiceSpec.Ports {
+		port := corev1.ServicePort{
+			Name: servicePort.Name,
+			Port: servicePort.Port,
+		}
+		if port.Name == "" && len(syrax.Spec.ServiceSpec.Ports) > 1 {
+			port.Name = fmt.Sprintf("port-%d", servicePort.Port)
+		}
+		if servicePort.NodePort != nil {
+			port.NodePort = *servicePort.NodePort
+		}
+		if servicePort.TargetPort != nil {
+			port.TargetPort.IntVal = *servicePort.TargetPort
+		}
+		ports = append(ports, port)
 	}
 	setOwner(service, syrax)
 
 	if syrax.ObjectMeta.Namespace != "" {
 		service.Namespace = syrax.ObjectMeta.Namespace
 	}
-	service.Labels = labels
-
-	if syrax.Spec.ServiceSpec.NodePort != nil {
-		ports[0].NodePort = *syrax.Spec.ServiceSpec.NodePort
-	}
-	if syrax.Spec.ServiceSpec.TargetPort != nil {
-		ports[0].TargetPort.IntVal = *syrax.Spec.ServiceSpec.TargetPort
-	}
+	service.Labels = withTemplateLabels(syrax, labels)
 
 	service.Spec.Ports = ports
-	service.Spec.Selector = labels
+	service.Spec.Selector = serviceSelector(syrax)
 
 	return service
 }
-func (r *SyraxReconciler) getDeploymentName(syrax *syraxv1.Syrax) string {
+func (r *SyraxReconciler) getDeploymentName(syrax *syraxv2.Syrax) string {
 	UID := syrax.UID
 	deploymentList := appsv1.DeploymentList{}
 	err := r.List(context.TODO(), &deploymentList, client.InNamespace(syrax.Namespace), client.MatchingLabels{"dracarys": "im-now-the-servant-of-the-white-walkers"})
+	// The deployment being renamed to keeps serving the syrax only once the
+	// old one is gone.
+	target := migrationTarget(syrax, kindDeployment)
+	migrating := ""
 	if err == nil {
 		for _, deployment := range deploymentList.Items {
+			if deployment.Labels[utils.TrackLabel] != "" || deployment.Labels[utils.ComponentLabel] != "" {
+				continue
+			}
 			if deployment.OwnerReferences != nil && deployment.OwnerReferences[0].UID == UID {
-				return deployment.Name
+				if deployment.Name != target {
+					return deployment.Name
+				}
+				migrating = deployment.Name
 			}
 		}
 	}
-	var deploymentName bytes.Buffer
-	deploymentName.WriteString(ToLowerCase(syrax.Name))
-	if syrax.Spec.DeploymentSpec.Name != "" {
-		deploymentName.WriteString("-")
-		deploymentName.WriteString(ToLowerCase(syrax.Spec.DeploymentSpec.Name))
+	if migrating != "" {
+		return migrating
 	}
+	return r.freeDeploymentName(syrax, childBaseName(syrax, syrax.Spec.DeploymentSpec.Name))
+}
 
+// freeDeploymentName returns the first name made of base and a numeric suffix
+// that no deployment uses yet.
+func (r *SyraxReconciler) freeDeploymentName(syrax *syraxv2.Syrax, base string) string {
 	for i := 0; i != -1; i++ {
-		name, err := r.deploymentNameIsExist(syrax, deploymentName.String(), int32(i))
+		name, err := r.deploymentNameIsExist(syrax, base, int32(i))
 		if err == nil {
+			if i > 0 {
+				r.warningEvent(syrax, ReasonNameConflict, fmt.Sprintf("deployment names %s-0 to %s-%d are taken, using %s", base, base, i-1, name))
+			}
 			return name
 		}
+		namingConflicts.WithLabelValues(kindDeployment).Inc()
 	}
 
-	return deploymentName.String()
+	return base
 }
 
-func (r *SyraxReconciler) deploymentNameIsExist(syrax *syraxv1.Syrax, name string, cnt int32) (string, error) {
+// apiReader returns the reader used to check whether a name is taken.
+func (r *SyraxReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+func (r *SyraxReconciler) deploymentNameIsExist(syrax *syraxv2.Syrax, name string, cnt int32) (string, error) {
 	_name := fmt.Sprintf("%s%s%s", name, "-", String(cnt))
 
-	err := r.Get(context.TODO(), namespcedname.NamespacedName{syrax.Namespace, _name}, &appsv1.Deployment{})
+	err := r.apiReader().Get(context.TODO(), namespcedname.NamespacedName{Namespace: syrax.Namespace, Name: _name}, &appsv1.Deployment{})
 	if err != nil {
 		return _name, nil
 	}
 	return "", fmt.Errorf("deployment Name has already occupied")
 
 }
-func (r *SyraxReconciler) getServiceName(syrax *syraxv1.Syrax) string {
+func (r *SyraxReconciler) getServiceName(syrax *syraxv2.Syrax) string {
 	UID := syrax.UID
 	serviceList := &corev1.ServiceList{}
 	err := r.List(context.TODO(), serviceList, client.InNamespace(syrax.Namespace), client.MatchingLabels{"dracarys": "im-now-the-servant-of-the-white-walkers"})
+	target := migrationTarget(syrax, kindService)
+	migrating := ""
 	if err == nil {
 		for _, service := range serviceList.Items {
+			if service.Labels[utils.TrackLabel] != "" || service.Labels[utils.ComponentLabel] != "" {
+				continue
+			}
 			if service.OwnerReferences != nil && service.OwnerReferences[0].UID == UID {
-				return service.Name
+				if service.Name != target {
+					return service.Name
+				}
+				migrating = service.Name
 			}
 		}
 	}
-
-	svcName := ToLowerCase(syrax.Name)
-	if syrax.Spec.ServiceSpec.Name != "" {
-		svcName = fmt.Sprintf("%s%s%s", svcName, "-", ToLowerCase(syrax.Spec.ServiceSpec.Name))
+	if migrating != "" {
+		return migrating
 	}
+	return r.freeServiceName(syrax, childBaseName(syrax, syrax.Spec.ServiceSpec.Name))
+}
 
+// freeServiceName returns the first name made of base and a numeric suffix
+// that no service uses yet.
+func (r *SyraxReconciler) freeServiceName(syrax *syraxv2.Syrax, base string) string {
 	for i := 0; i != -1; i++ {
-		name, err := r.serviceNameExist(syrax, svcName, int32(i))
+		name, err := r.serviceNameExist(syrax, base, int32(i))
 		if err == nil {
+			if i > 0 {
+				r.warningEvent(syrax, ReasonNameConflict, fmt.Sprintf("service names %s-0 to %s-%d are taken, using %s", base, base, i-1, name))
+			}
 			return name
 		}
+		namingConflicts.WithLabelValues(kindService).Inc()
 	}
 
-	return svcName
+	return base
 }
 
-func (r *SyraxReconciler) serviceNameExist(syrax *syraxv1.Syrax, name string, cnt int32) (string, error) {
+func (r *SyraxReconciler) serviceNameExist(syrax *syraxv2.Syrax, name string, cnt int32) (string, error) {
 	_name := fmt.Sprintf("%s%s%s", name, "-", String(cnt))
-	err := r.Get(context.TODO(), namespcedname.NamespacedName{syrax.Namespace, _name}, &corev1.Service{})
+	err := r.apiReader().Get(context.TODO(), namespcedname.NamespacedName{Namespace: syrax.Namespace, Name: _name}, &corev1.Service{})
 
 	if err != nil {
 		return _name, nil
@@ -176,13 +320,50 @@ func (r *SyraxReconciler) serviceNameExist(syrax *syraxv1.Syrax, name string, cn
 
 }
 
-func ifDeployUpdated(syrax *syraxv1.Syrax, deployment *appsv1.Deployment) bool {
+// logDrift logs the difference between the current and the desired spec of a
+// drifted child. The diff is only computed at verbosity 2 and above.
+func logDrift(logger logr.Logger, kind string, current, desired interface{}) {
+	logger.Info("Correcting drifted child", "kind", kind)
+	if diffLogger := logger.V(2); diffLogger.Enabled() {
+		diffLogger.Info("Drifted child diff", "kind", kind, "diff", cmp.Diff(current, desired))
+	}
+}
+
+func ifDeployUpdated(syrax *syraxv2.Syrax, deployment *appsv1.Deployment) bool {
 	if (syrax.Spec.DeploymentSpec.Replicas != nil && *syrax.Spec.DeploymentSpec.Replicas != *deployment.Spec.Replicas) == true {
 		return true
 	}
 	if syrax.Spec.DeploymentSpec.Image != "" && syrax.Spec.DeploymentSpec.Image != deployment.Spec.Template.Spec.Containers[0].Image {
 		return true
 	}
+	if ifStrategyUpdated(syrax, deployment) {
+		return true
+	}
+	if envUpdated(syrax, deployment) {
+		return true
+	}
+	if containerSpecUpdated(syrax, deployment) {
+		return true
+	}
+	if imagePullUpdated(syrax, deployment) {
+		return true
+	}
+	if configFilesUpdated(syrax, deployment) {
+		return true
+	}
+	if restartUpdated(syrax, deployment) {
+		return true
+	}
+	if templateLabelsUpdated(syrax, deployment.Spec.Template.Labels) {
+		return true
+	}
+	if syrax.Spec.DeploymentSpec.MinReadySeconds != deployment.Spec.MinReadySeconds {
+		return true
+	}
+	if syrax.Spec.DeploymentSpec.ProgressDeadlineSeconds != nil && (deployment.Spec.ProgressDeadlineSeconds == nil ||
+		*syrax.Spec.DeploymentSpec.ProgressDeadlineSeconds != *deployment.Spec.ProgressDeadlineSeconds) {
+		return true
+	}
 	if (deployment.OwnerReferences == nil && syrax.DeletionTimestamp == nil) ||
 		(deployment.OwnerReferences != nil && syrax.DeletionTimestamp != nil) {
 		return true
@@ -190,17 +371,93 @@ func ifDeployUpdated(syrax *syraxv1.Syrax, deployment *appsv1.Deployment) bool {
 	return false
 
 }
-func ifSvcUpdated(syrax *syraxv1.Syrax, service *corev1.Service) bool {
-	if (syrax.Spec.ServiceSpec.Port != nil && *syrax.Spec.ServiceSpec.Port != service.Spec.Ports[0].Port) ||
-		(syrax.Spec.ServiceSpec.NodePort != nil && *syrax.Spec.ServiceSpec.NodePort != service.Spec.Ports[0].NodePort) ||
-		(syrax.Spec.ServiceSpec.TargetPort != nil && *syrax.Spec.ServiceSpec.TargetPort != service.Spec.Ports[0].TargetPort.IntVal) ||
+
+// envUpdated reports whether the env of the container differs from the env
+// of the syrax. Fields defaulted by the API server are ignored.
+func envUpdated(syrax *syraxv2.Syrax, deployment *appsv1.Deployment) bool {
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name == utils.ContainerName {
+			return len(container.Env) != len(syrax.Spec.Env) ||
+				!equality.Semantic.DeepDerivative(syrax.Spec.Env, container.Env)
+		}
+	}
+	return false
+}
+
+// containerSpecUpdated reports whether the resources or the probes of the
+// container differ from those of the syrax. Fields defaulted by the API server
+// are ignored.
+func containerSpecUpdated(syrax *syraxv2.Syrax, deployment *appsv1.Deployment) bool {
+	spec := syrax.Spec.DeploymentSpec
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name != utils.ContainerName {
+			continue
+		}
+		if spec.Resources == nil {
+			if len(container.Resources.Limits) != 0 || len(container.Resources.Requests) != 0 {
+				return true
+			}
+		} else if !equality.Semantic.DeepDerivative(*spec.Resources, container.Resources) {
+			return true
+		}
+		return probeUpdated(spec.LivenessProbe, container.LivenessProbe) ||
+			probeUpdated(spec.ReadinessProbe, container.ReadinessProbe)
+	}
+	return false
+}
+
+func probeUpdated(desired, current *corev1.Probe) bool {
+	if desired == nil {
+		return current != nil
+	}
+	return current == nil || !equality.Semantic.DeepDerivative(*defaultedProbe(desired), *current)
+}
+
+func ifStrategyUpdated(syrax *syraxv2.Syrax, deployment *appsv1.Deployment) bool {
+	desired := deploymentStrategy(syrax)
+	if desired.Type != deployment.Spec.Strategy.Type {
+		return true
+	}
+	if desired.RollingUpdate == nil {
+		return false
+	}
+	current := deployment.Spec.Strategy.RollingUpdate
+	if current == nil {
+		return true
+	}
+	if desired.RollingUpdate.MaxSurge != nil && (current.MaxSurge == nil || *desired.RollingUpdate.MaxSurge != *current.MaxSurge) {
+		return true
+	}
+	if desired.RollingUpdate.MaxUnavailable != nil && (current.MaxUnavailable == nil || *desired.RollingUpdate.MaxUnavailable != *current.MaxUnavailable) {
+		return true
+	}
+	return false
+}
+func ifSvcUpdated(syrax *syraxv2.Syrax, service *corev1.Service) bool {
+	if ifSvcPortsUpdated(syrax, service) ||
 		(service.Spec.Type != syrax.Spec.ServiceSpec.ServiceType) ||
+		!equality.Semantic.DeepEqual(service.Spec.Selector, serviceSelector(syrax)) ||
 		(service.OwnerReferences == nil && syrax.DeletionTimestamp == nil) ||
 		(service.OwnerReferences != nil && syrax.DeletionTimestamp != nil) {
 		return true
 	}
 	return false
 }
+func ifSvcPortsUpdated(syrax *syraxv2.Syrax, service *corev1.Service) bool {
+	if len(syrax.Spec.ServiceSpec.Ports) != len(service.Spec.Ports) {
+		return true
+	}
+	for i, port := range syrax.Spec.ServiceSpec.Ports {
+		current := service.Spec.Ports[i]
+		if port.Port != current.Port ||
+			(port.Name != "" && port.Name != current.Name) ||
+			(port.NodePort != nil && *port.NodePort != current.NodePort) ||
+			(port.TargetPort != nil && *port.TargetPort != current.TargetPort.IntVal) {
+			return true
+		}
+	}
+	return false
+}
 func String(n int32) string {
 	buf := [11]byte{}
 	pos := len(buf)
@@ -242,7 +499,7 @@ func getNameExclSerial(name string) string {
 	}
 	var result string = ""
 	for begin <= end {
-		result = fmt.Sprintf("%s%s", result, name[begin])
+		result = fmt.Sprintf("%s%c", result, name[begin])
 		begin++
 	}
 	return result
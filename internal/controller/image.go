@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	namespcedname "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+)
+
+// defaultImagePolicyInterval is the interval of the Track image policy.
+const defaultImagePolicyInterval = 5 * time.Minute
+
+// resolveImages replaces the tag of the images of the deployments with an
+// image policy by the digest it points to, and records the digests in the
+// status. The spec is only changed in memory. Deployments sharing an image
+// share its digest, which is tracked if any of them tracks it. It returns the
+// time until a tracked tag is due to be resolved again, or zero. The images
+// are resolved before any deployment is created or renamed, so that every
+// deployment of the syrax runs the digests.
+func (r *SyraxReconciler) resolveImages(ctx context.Context, syrax *syraxv2.Syrax) (time.Duration, error) {
+	deployments := []*syraxv2.DeploymentSpec{&syrax.Spec.DeploymentSpec}
+	for i := range syrax.Spec.Components {
+		deployments = append(deployments, &syrax.Spec.Components[i].DeploymentSpec)
+	}
+	previous := map[string]syraxv2.ResolvedImage{}
+	for _, resolved := range syrax.Status.ResolvedImages {
+		previous[resolved.Image] = resolved
+	}
+	// the interval each image is resolved again after, zero when pinned.
+	intervals := map[string]time.Duration{}
+	for _, deployment := range deployments {
+		if deployment.ImagePolicy == nil || strings.Contains(deployment.Image, "@") {
+			continue
+		}
+		interval := intervals[deployment.Image]
+		if deployment.ImagePolicy.Mode == syraxv2.ImagePolicyTrack {
+			if current := imagePolicyInterval(deployment.ImagePolicy); interval == 0 || current < interval {
+				interval = current
+			}
+		}
+		intervals[deployment.Image] = interval
+	}
+	if len(intervals) == 0 {
+		syrax.Status.ResolvedImages = nil
+		return 0, nil
+	}
+
+	var pullSecrets []corev1.Secret
+	resolved := map[string]syraxv2.ResolvedImage{}
+	var requeueAfter time.Duration
+	now := time.Now()
+	for image, interval := range intervals {
+		last, found := previous[image]
+		due := !found || (interval > 0 && !now.Before(last.ResolvedAt.Add(interval)))
+		if due {
+			if pullSecrets == nil {
+				var err error
+				if pullSecrets, err = r.pullSecrets(ctx, syrax); err != nil {
+					return 0, err
+				}
+			}
+			digest, err := r.imageResolver().Resolve(ctx, image, pullSecrets)
+			switch {
+			case err != nil && !found:
+				r.warningEvent(syrax, ReasonImageResolveFailed, fmt.Sprintf("unable to resolve image %s: %v", image, err))
+				return 0, fmt.Errorf("unable to resolve image %s: %w", image, err)
+			case err != nil:
+				// keep running the last digest until the registry is back.
+				log.FromContext(ctx).Error(err, "Unable to resolve tracked image", "image", image)
+				r.warningEvent(syrax, ReasonImageResolveFailed, fmt.Sprintf("unable to resolve image %s, keeping %s: %v", image, last.Digest, err))
+			default:
+				if found && digest != last.Digest {
+					r.normalEvent(syrax, ReasonImageResolved, fmt.Sprintf("image %s moved from %s to %s", image, last.Digest, digest))
+				} else if !found {
+					r.normalEvent(syrax, ReasonImageResolved, fmt.Sprintf("resolved image %s to %s", image, digest))
+				}
+				last = syraxv2.ResolvedImage{Image: image, Digest: digest, ResolvedAt: metav1.NewTime(now)}
+			}
+		}
+		resolved[image] = last
+		if interval > 0 {
+			next := last.ResolvedAt.Add(interval).Sub(now)
+			if next <= 0 {
+				next = interval
+			}
+			if requeueAfter == 0 || next < requeueAfter {
+				requeueAfter = next
+			}
+		}
+	}
+
+	for _, deployment := range deployments {
+		if last, ok := resolved[deployment.Image]; ok {
+			deployment.Image = deployment.Image + "@" + last.Digest
+		}
+	}
+	syrax.Status.ResolvedImages = make([]syraxv2.ResolvedImage, 0, len(resolved))
+	for _, last := range resolved {
+		syrax.Status.ResolvedImages = append(syrax.Status.ResolvedImages, last)
+	}
+	sort.Slice(syrax.Status.ResolvedImages, func(i, j int) bool {
+		return syrax.Status.ResolvedImages[i].Image < syrax.Status.ResolvedImages[j].Image
+	})
+	return requeueAfter, nil
+}
+
+// imagePolicyInterval returns the interval of a Track image policy.
+func imagePolicyInterval(policy *syraxv2.ImagePolicy) time.Duration {
+	if policy.Interval != nil && policy.Interval.Duration > 0 {
+		return policy.Interval.Duration
+	}
+	return defaultImagePolicyInterval
+}
+
+// imageResolver returns the resolver of the image policies.
+func (r *SyraxReconciler) imageResolver() ImageResolver {
+	if r.ImageResolver != nil {
+		return r.ImageResolver
+	}
+	return &RegistryResolver{}
+}
+
+// pullSecrets returns the pull secrets of the syrax that exist. They are read
+// from the API server, as the cache may only hold the children of syraxes.
+// The registry credentials are read from their source, as the images are
+// resolved before the source is copied.
+func (r *SyraxReconciler) pullSecrets(ctx context.Context, syrax *syraxv2.Syrax) ([]corev1.Secret, error) {
+	keys := []namespcedname.NamespacedName{}
+	for _, ref := range syrax.Spec.DeploymentSpec.ImagePullSecrets {
+		keys = append(keys, namespcedname.NamespacedName{Namespace: syrax.Namespace, Name: ref.Name})
+	}
+	if ref := syrax.Spec.RegistryCredentialsRef; ref != nil && slices.Contains(r.RegistryCredentialsNamespaces, ref.Namespace) {
+		keys = append(keys, namespcedname.NamespacedName{Namespace: ref.Namespace, Name: ref.Name})
+	}
+	secrets := []corev1.Secret{}
+	for _, key := range keys {
+		secret := corev1.Secret{}
+		err := r.apiReader().Get(ctx, key, &secret)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+// requeueForImages resyncs a syrax when one of its tracked tags is due to be
+// resolved again.
+func requeueForImages(result ctrl.Result, requeueAfter time.Duration) ctrl.Result {
+	if requeueAfter > 0 && !requeuesNow(result) && (result.RequeueAfter == 0 || requeueAfter < result.RequeueAfter) {
+		result.RequeueAfter = requeueAfter
+	}
+	return result
+}
@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+)
+
+// tagResolver resolves the images from a map of tags to digests.
+type tagResolver map[string]string
+
+func (t tagResolver) Resolve(ctx context.Context, image string, pullSecrets []corev1.Secret) (string, error) {
+	digest, ok := t[image]
+	if !ok {
+		return "", fmt.Errorf("manifest unknown")
+	}
+	return digest, nil
+}
+
+// localRegistry stands in for a registry serving the manifests of the tags,
+// behind the bearer token flow when token is set.
+func localRegistry(tags map[string]string, token string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/token" {
+			Expect(req.URL.Query().Get("scope")).To(Equal("repository:shop/api:pull"))
+			fmt.Fprintf(w, `{"token":%q}`, token)
+			return
+		}
+		if token != "" && req.Header.Get("Authorization") != "Bearer "+token {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:shop/api:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		Expect(req.Header.Get("Accept")).To(ContainSubstring("application/vnd.oci.image.index.v1+json"))
+		digest, ok := tags[strings.TrimPrefix(req.URL.Path, "/v2/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", digest)
+	}))
+	return server
+}
+
+var _ = Describe("Syrax image policies", func() {
+	const resourceName = "image-resource"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+
+	var controllerReconciler *SyraxReconciler
+	var resolver tagResolver
+
+	BeforeEach(func() {
+		resource := newSyrax(typeNamespacedName)
+		resource.Spec.DeploymentSpec.Image = "shop/api:1.0"
+		resource.Spec.DeploymentSpec.ImagePolicy = &targaryenv2.ImagePolicy{Mode: targaryenv2.ImagePolicyPin}
+		resource.Spec.ServiceSpec.ServiceType = corev1.ServiceTypeClusterIP
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+		resolver = tagResolver{"shop/api:1.0": "sha256:aaaa"}
+		controllerReconciler = newReconciler()
+		controllerReconciler.ImageResolver = resolver
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+		deleteOwnedChildren(ctx, typeNamespacedName)
+	})
+
+	It("should pin the tag to its digest once", func() {
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		deployment := ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("shop/api:1.0@sha256:aaaa"))
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(syrax.Spec.DeploymentSpec.Image).To(Equal("shop/api:1.0"))
+		Expect(syrax.Status.ResolvedImages).To(HaveLen(1))
+		Expect(syrax.Status.ResolvedImages[0].Image).To(Equal("shop/api:1.0"))
+		Expect(syrax.Status.ResolvedImages[0].Digest).To(Equal("sha256:aaaa"))
+
+		By("keeping the digest when the tag moves")
+		resolver["shop/api:1.0"] = "sha256:bbbb"
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		deployment = ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("shop/api:1.0@sha256:aaaa"))
+	})
+
+	It("should run the pinned digest on a renamed deployment", func() {
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		resolver["shop/api:1.0"] = "sha256:bbbb"
+
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Spec.DeploymentSpec.Name = "api"
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		renamed := &appsv1.Deployment{}
+		name := types.NamespacedName{Name: childBaseName(syrax, "api") + "-0", Namespace: "default"}
+		Expect(k8sClient.Get(ctx, name, renamed)).To(Succeed())
+		Expect(renamed.Spec.Template.Spec.Containers[0].Image).To(Equal("shop/api:1.0@sha256:aaaa"))
+	})
+
+	It("should roll out the new digest of a tracked tag", func() {
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Spec.DeploymentSpec.ImagePolicy = &targaryenv2.ImagePolicy{
+			Mode:     targaryenv2.ImagePolicyTrack,
+			Interval: &metav1.Duration{Duration: time.Minute},
+		}
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+
+		result := reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(result.RequeueAfter).To(BeNumerically("~", time.Minute, time.Second))
+
+		By("resolving the tag again once the interval passed")
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Status.ResolvedImages[0].ResolvedAt = metav1.NewTime(time.Now().Add(-2 * time.Minute))
+		Expect(k8sClient.Status().Update(ctx, syrax)).To(Succeed())
+		resolver["shop/api:1.0"] = "sha256:bbbb"
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		deployment := ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("shop/api:1.0@sha256:bbbb"))
+
+		By("keeping the last digest while the registry fails")
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Status.ResolvedImages[0].ResolvedAt = metav1.NewTime(time.Now().Add(-2 * time.Minute))
+		Expect(k8sClient.Status().Update(ctx, syrax)).To(Succeed())
+		delete(resolver, "shop/api:1.0")
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		deployment = ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("shop/api:1.0@sha256:bbbb"))
+	})
+
+	It("should resolve tags with the registry API", func() {
+		registry := localRegistry(map[string]string{"shop/api/manifests/1.0": "sha256:cccc"}, "secret-token")
+		defer registry.Close()
+		host := strings.TrimPrefix(registry.URL, "https://")
+		resolver := &RegistryResolver{Client: registry.Client()}
+
+		digest, err := resolver.Resolve(ctx, host+"/shop/api:1.0", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(digest).To(Equal("sha256:cccc"))
+
+		_, err = resolver.Resolve(ctx, host+"/shop/api:2.0", nil)
+		Expect(err).To(MatchError(ContainSubstring("404")))
+	})
+
+	It("should find the credentials of a registry in the pull secrets", func() {
+		secret := corev1.Secret{
+			Type: corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(
+				`{"auths":{"https://index.docker.io/v1/":{"auth":"dXNlcjpwYXNz"},"registry.example.com":{"username":"bot","password":"token"}}}`)},
+		}
+		username, password := registryCredentials("docker.io", []corev1.Secret{secret})
+		Expect([]string{username, password}).To(Equal([]string{"user", "pass"}))
+		username, password = registryCredentials("registry.example.com", []corev1.Secret{secret})
+		Expect([]string{username, password}).To(Equal([]string{"bot", "token"}))
+		username, _ = registryCredentials("quay.io", []corev1.Secret{secret})
+		Expect(username).To(BeEmpty())
+	})
+})
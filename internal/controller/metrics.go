@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	namespcedname "k8s.io/apimachinery/pkg/types"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Phases a syrax is counted under by the syrax_objects metric.
+const (
+	phaseReady       = "Ready"
+	phaseProgressing = "Progressing"
+	phasePaused      = "Paused"
+	phaseRolledBack  = "RolledBack"
+	phaseTerminating = "Terminating"
+)
+
+// Outcomes of an operation on a child object.
+const (
+	outcomeSuccess = "success"
+	outcomeError   = "error"
+)
+
+var (
+	syraxObjects = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "syrax_objects",
+		Help: "Number of syrax objects by phase.",
+	}, []string{"phase"})
+
+	syraxDesiredReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "syrax_desired_replicas",
+		Help: "Number of replicas requested by the spec of a syrax.",
+	}, []string{"namespace", "name"})
+
+	syraxAvailableReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "syrax_available_replicas",
+		Help: "Number of available replicas of the deployment serving a syrax.",
+	}, []string{"namespace", "name"})
+
+	childOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "syrax_child_operations_total",
+		Help: "Number of create, update and delete calls on the children of syrax objects.",
+	}, []string{"kind", "operation", "outcome"})
+
+	driftCorrections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "syrax_drift_corrections_total",
+		Help: "Number of children updated because they drifted from the syrax spec.",
+	}, []string{"kind"})
+
+	namingConflicts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "syrax_naming_conflicts_total",
+		Help: "Number of child names skipped because they were already taken.",
+	}, []string{"kind"})
+
+	finalizerCleanupDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "syrax_finalizer_cleanup_duration_seconds",
+		Help:    "Time between the deletion of a syrax and the removal of its finalizer.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		syraxObjects,
+		syraxDesiredReplicas,
+		syraxAvailableReplicas,
+		childOperations,
+		driftCorrections,
+		namingConflicts,
+		finalizerCleanupDuration,
+	)
+}
+
+// syraxPhases remembers the phase every syrax was last counted under, so that
+// syrax_objects can be moved between phases and decremented on deletion.
+var syraxPhases = struct {
+	sync.Mutex
+	phases map[namespcedname.NamespacedName]string
+}{phases: map[namespcedname.NamespacedName]string{}}
+
+// syraxPhase summarizes the status of a syrax into a single phase.
+func syraxPhase(syrax *syraxv2.Syrax) string {
+	switch {
+	case syrax.DeletionTimestamp != nil:
+		return phaseTerminating
+	case meta.IsStatusConditionTrue(syrax.Status.Conditions, syraxv2.ConditionPaused):
+		return phasePaused
+	case meta.IsStatusConditionTrue(syrax.Status.Conditions, syraxv2.ConditionRolledBack):
+		return phaseRolledBack
+	case meta.IsStatusConditionTrue(syrax.Status.Conditions, syraxv2.ConditionReady):
+		return phaseReady
+	}
+	return phaseProgressing
+}
+
+// recordSyraxMetrics updates the per-syrax metrics from its status.
+func recordSyraxMetrics(syrax *syraxv2.Syrax) {
+	key := namespcedname.NamespacedName{Namespace: syrax.Namespace, Name: syrax.Name}
+	phase := syraxPhase(syrax)
+
+	syraxPhases.Lock()
+	if previous, ok := syraxPhases.phases[key]; !ok || previous != phase {
+		if ok {
+			syraxObjects.WithLabelValues(previous).Dec()
+		}
+		syraxObjects.WithLabelValues(phase).Inc()
+		syraxPhases.phases[key] = phase
+	}
+	syraxPhases.Unlock()
+
+	syraxDesiredReplicas.WithLabelValues(syrax.Namespace, syrax.Name).Set(float64(desiredReplicas(syrax)))
+	available := int32(0)
+	if syrax.Status.AvailableReplicas != nil {
+		available = *syrax.Status.AvailableReplicas
+	}
+	syraxAvailableReplicas.WithLabelValues(syrax.Namespace, syrax.Name).Set(float64(available))
+}
+
+// forgetSyraxMetrics drops the metrics of a syrax that no longer exists.
+func forgetSyraxMetrics(key namespcedname.NamespacedName) {
+	syraxPhases.Lock()
+	if phase, ok := syraxPhases.phases[key]; ok {
+		syraxObjects.WithLabelValues(phase).Dec()
+		delete(syraxPhases.phases, key)
+	}
+	syraxPhases.Unlock()
+
+	syraxDesiredReplicas.DeleteLabelValues(key.Namespace, key.Name)
+	syraxAvailableReplicas.DeleteLabelValues(key.Namespace, key.Name)
+}
+
+// observeFinalizerCleanup records how long the cleanup of a deleted syrax took.
+func observeFinalizerCleanup(syrax *syraxv2.Syrax) {
+	if syrax.DeletionTimestamp == nil {
+		return
+	}
+	finalizerCleanupDuration.Observe(time.Since(syrax.DeletionTimestamp.Time).Seconds())
+}
+
+// childKind returns the kind of a child object for metric labels.
+func (r *SyraxReconciler) childKind(obj client.Object) string {
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return "Unknown"
+	}
+	return gvk.Kind
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return outcomeError
+	}
+	return outcomeSuccess
+}
+
+// logChildOperation counts an operation on a child object and logs it with the
+// kind and name of the child.
+func (r *SyraxReconciler) logChildOperation(ctx context.Context, obj client.Object, operation string, err error) {
+	kind := r.childKind(obj)
+	childOperations.WithLabelValues(kind, operation, outcome(err)).Inc()
+
+	logger := log.FromContext(ctx).WithValues("kind", kind, "child", obj.GetName(), "action", operation)
+	if err != nil {
+		logger.V(1).Info("Child operation failed", "error", err.Error())
+		return
+	}
+	logger.Info("Child operation succeeded")
+}
+
+// createChild creates a child object and counts the call.
+func (r *SyraxReconciler) createChild(ctx context.Context, obj client.Object) error {
+	ctx, span := r.startChildSpan(ctx, "Create", obj, obj.GetName())
+	err := r.Create(ctx, obj)
+	endSpan(span, err)
+	r.logChildOperation(ctx, obj, "create", err)
+	return err
+}
+
+// updateChild updates a child object and counts the call.
+func (r *SyraxReconciler) updateChild(ctx context.Context, obj client.Object) error {
+	ctx, span := r.startChildSpan(ctx, "Update", obj, obj.GetName())
+	err := r.Update(ctx, obj)
+	endSpan(span, err)
+	r.logChildOperation(ctx, obj, "update", err)
+	return err
+}
+
+// deleteChild deletes a child object if it exists and counts the call.
+func (r *SyraxReconciler) deleteChild(ctx context.Context, obj client.Object) error {
+	ctx, span := r.startChildSpan(ctx, "Delete", obj, obj.GetName())
+	err := r.Delete(ctx, obj)
+	endSpan(span, client.IgnoreNotFound(err))
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	r.logChildOperation(ctx, obj, "delete", err)
+	return err
+}
+
+// correctDrift updates a child that drifted from the syrax spec.
+func (r *SyraxReconciler) correctDrift(ctx context.Context, obj client.Object) error {
+	err := r.updateChild(ctx, obj)
+	if err == nil {
+		driftCorrections.WithLabelValues(r.childKind(obj)).Inc()
+	}
+	return err
+}
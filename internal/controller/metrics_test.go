@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+)
+
+var _ = Describe("Syrax metrics", func() {
+	const resourceName = "metrics-resource"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+
+	BeforeEach(func() {
+		resource := newSyrax(typeNamespacedName)
+		resource.Spec.DeploymentSpec.Replicas = ptr.To[int32](3)
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+	})
+
+	It("should count child operations, drift corrections and replicas", func() {
+		controllerReconciler := newReconciler()
+		created := testutil.ToFloat64(childOperations.WithLabelValues("Deployment", "create", outcomeSuccess))
+		drifted := testutil.ToFloat64(driftCorrections.WithLabelValues("Deployment"))
+
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(testutil.ToFloat64(childOperations.WithLabelValues("Deployment", "create", outcomeSuccess))).To(Equal(created + 1))
+		Expect(testutil.ToFloat64(syraxDesiredReplicas.WithLabelValues("default", resourceName))).To(Equal(3.0))
+		Expect(testutil.ToFloat64(syraxAvailableReplicas.WithLabelValues("default", resourceName))).To(Equal(0.0))
+		Expect(testutil.ToFloat64(syraxObjects.WithLabelValues(phaseProgressing))).To(BeNumerically(">=", 1))
+
+		By("Scaling the deployment by hand")
+		deployment := ownedDeployment(ctx, typeNamespacedName)
+		deployment.Spec.Replicas = ptr.To[int32](1)
+		Expect(k8sClient.Update(ctx, deployment)).To(Succeed())
+
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(testutil.ToFloat64(driftCorrections.WithLabelValues("Deployment"))).To(Equal(drifted + 1))
+	})
+
+	It("should drop the metrics of a deleted syrax", func() {
+		recordSyraxMetrics(&targaryenv2.Syrax{
+			ObjectMeta: metav1.ObjectMeta{Name: "gone", Namespace: "default"},
+		})
+		progressing := testutil.ToFloat64(syraxObjects.WithLabelValues(phaseProgressing))
+
+		forgetSyraxMetrics(types.NamespacedName{Name: "gone", Namespace: "default"})
+		Expect(testutil.ToFloat64(syraxObjects.WithLabelValues(phaseProgressing))).To(Equal(progressing - 1))
+		Expect(syraxDesiredReplicas.DeleteLabelValues("default", "gone")).To(BeFalse())
+	})
+})
@@ -0,0 +1,256 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	namespcedname "k8s.io/apimachinery/pkg/types"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Kinds of the children whose renames are recorded in the syrax status.
+const (
+	kindDeployment = "Deployment"
+	kindService    = "Service"
+)
+
+// childBaseName returns the name the children of the syrax get for the given
+// name in the spec, before the numeric suffix that avoids conflicts.
+func childBaseName(syrax *syraxv2.Syrax, specName string) string {
+	name := ToLowerCase(syrax.Name)
+	if specName != "" {
+		name += "-" + ToLowerCase(specName)
+	}
+	return name
+}
+
+// namedAfter reports whether name is base followed by a numeric suffix.
+func namedAfter(name, base string) bool {
+	suffix, ok := strings.CutPrefix(name, base+"-")
+	if !ok || suffix == "" {
+		return false
+	}
+	for _, c := range suffix {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func findMigration(syrax *syraxv2.Syrax, kind string) *syraxv2.NameMigration {
+	for i := range syrax.Status.Migrations {
+		if syrax.Status.Migrations[i].Kind == kind {
+			return &syrax.Status.Migrations[i]
+		}
+	}
+	return nil
+}
+
+// migrationTarget returns the name a child of the given kind is being renamed
+// to, if a rename is in progress.
+func migrationTarget(syrax *syraxv2.Syrax, kind string) string {
+	migration := findMigration(syrax, kind)
+	if migration == nil || migration.Phase != syraxv2.MigrationPhaseProgressing {
+		return ""
+	}
+	return migration.To
+}
+
+// setMigration records a new migration of a child, replacing the last one of
+// the same kind.
+func setMigration(syrax *syraxv2.Syrax, migration syraxv2.NameMigration) *syraxv2.NameMigration {
+	if current := findMigration(syrax, migration.Kind); current != nil {
+		*current = migration
+		return current
+	}
+	syrax.Status.Migrations = append(syrax.Status.Migrations, migration)
+	return &syrax.Status.Migrations[len(syrax.Status.Migrations)-1]
+}
+
+// migrateDeployment renames the deployment of the syrax once its name in the
+// spec changed. The deployment under the new name is rolled out next to the
+// current one, which keeps serving until the new one is available and is
+// deleted afterwards. It returns the name of the deployment serving the syrax.
+func (r *SyraxReconciler) migrateDeployment(ctx context.Context, syrax *syraxv2.Syrax, current string) (string, error) {
+	base := childBaseName(syrax, syrax.Spec.DeploymentSpec.Name)
+	if namedAfter(current, base) || syrax.DeletionTimestamp != nil {
+		return current, r.abortMigration(ctx, syrax, kindDeployment, current, &appsv1.Deployment{})
+	}
+	// The canary and blue-green deployments are named after the primary one,
+	// so the rename waits for these rollout modes to be turned off.
+	if canaryEnabled(syrax) || blueGreenEnabled(syrax) {
+		r.deferMigration(syrax, kindDeployment, current)
+		return current, nil
+	}
+	migration, err := r.startMigration(ctx, syrax, kindDeployment, current, base, &appsv1.Deployment{}, r.freeDeploymentName)
+	if err != nil {
+		return current, err
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := r.getChild(ctx, namespcedname.NamespacedName{Namespace: syrax.Namespace, Name: migration.To}, deployment); err != nil {
+		if !errors.IsNotFound(err) {
+			return current, err
+		}
+		r.newDeployment(syrax, migration.To, deployment)
+		if err := r.createChild(ctx, deployment); err != nil {
+			return current, err
+		}
+		r.normalEvent(syrax, ReasonCreated, fmt.Sprintf("created deployment %s", migration.To))
+	} else if ifDeployUpdated(syrax, deployment) {
+		r.newDeployment(syrax, migration.To, deployment)
+		if err := r.updateChild(ctx, deployment); err != nil {
+			return current, err
+		}
+	}
+	if !rolloutComplete(deployment) {
+		return current, nil
+	}
+
+	old := &appsv1.Deployment{}
+	old.Name = current
+	old.Namespace = syrax.Namespace
+	if err := r.deleteChild(ctx, old); err != nil {
+		return current, err
+	}
+	r.completeMigration(syrax, migration)
+	return migration.To, nil
+}
+
+// migrateService renames the service of the syrax once its name in the spec
+// changed. The service under the new name selects the same pods as the
+// current one, which is deleted once the new one is reachable. Services with
+// node ports are replaced at once since both cannot hold the same ports. It
+// returns the name of the service serving the syrax.
+func (r *SyraxReconciler) migrateService(ctx context.Context, syrax *syraxv2.Syrax, current string) (string, error) {
+	base := childBaseName(syrax, syrax.Spec.ServiceSpec.Name)
+	if namedAfter(current, base) || syrax.DeletionTimestamp != nil {
+		return current, r.abortMigration(ctx, syrax, kindService, current, &corev1.Service{})
+	}
+	// The preview service of a blue-green rollout is named after the main one.
+	if blueGreenEnabled(syrax) {
+		r.deferMigration(syrax, kindService, current)
+		return current, nil
+	}
+	migration, err := r.startMigration(ctx, syrax, kindService, current, base, &corev1.Service{}, r.freeServiceName)
+	if err != nil {
+		return current, err
+	}
+
+	old := &corev1.Service{}
+	old.Name = current
+	old.Namespace = syrax.Namespace
+	if hasNodePorts(syrax) {
+		if err := r.deleteChild(ctx, old); err != nil {
+			return current, err
+		}
+	}
+
+	service := &corev1.Service{}
+	if err := r.getChild(ctx, namespcedname.NamespacedName{Namespace: syrax.Namespace, Name: migration.To}, service); err != nil {
+		if !errors.IsNotFound(err) {
+			return current, err
+		}
+		service = r.newService(syrax, migration.To, service)
+		if err := r.createChild(ctx, service); err != nil {
+			return current, err
+		}
+		r.normalEvent(syrax, ReasonCreated, fmt.Sprintf("created service %s", migration.To))
+	}
+	if service.Spec.Type == corev1.ServiceTypeLoadBalancer && len(service.Status.LoadBalancer.Ingress) == 0 {
+		return current, nil
+	}
+
+	if err := r.deleteChild(ctx, old); err != nil {
+		return current, err
+	}
+	r.completeMigration(syrax, migration)
+	return migration.To, nil
+}
+
+// startMigration returns the migration of the child from its current name to
+// a name made of base, picking a free name when the migration starts. A child
+// created for an earlier rename to another name is deleted.
+func (r *SyraxReconciler) startMigration(ctx context.Context, syrax *syraxv2.Syrax, kind, current, base string, obj client.Object, freeName func(*syraxv2.Syrax, string) string) (*syraxv2.NameMigration, error) {
+	migration := findMigration(syrax, kind)
+	if migration != nil && migration.Phase == syraxv2.MigrationPhaseProgressing && migration.From == current {
+		if namedAfter(migration.To, base) {
+			return migration, nil
+		}
+		if err := r.deleteMigrationTarget(ctx, syrax, migration, obj); err != nil {
+			return nil, err
+		}
+	}
+
+	now := metav1.Now()
+	migration = setMigration(syrax, syraxv2.NameMigration{
+		Kind:      kind,
+		From:      current,
+		To:        freeName(syrax, base),
+		Phase:     syraxv2.MigrationPhaseProgressing,
+		StartedAt: &now,
+	})
+	r.normalEvent(syrax, ReasonRenaming, fmt.Sprintf("renaming %s %s to %s", strings.ToLower(kind), migration.From, migration.To))
+	return migration, nil
+}
+
+// deferMigration records a migration that cannot start yet.
+func (r *SyraxReconciler) deferMigration(syrax *syraxv2.Syrax, kind, current string) {
+	if migration := findMigration(syrax, kind); migration != nil &&
+		migration.Phase == syraxv2.MigrationPhasePending && migration.From == current {
+		return
+	}
+	setMigration(syrax, syraxv2.NameMigration{Kind: kind, From: current, Phase: syraxv2.MigrationPhasePending})
+}
+
+func (r *SyraxReconciler) completeMigration(syrax *syraxv2.Syrax, migration *syraxv2.NameMigration) {
+	now := metav1.Now()
+	migration.Phase = syraxv2.MigrationPhaseCompleted
+	migration.CompletedAt = &now
+	r.normalEvent(syrax, ReasonRenamed, fmt.Sprintf("renamed %s %s to %s", strings.ToLower(migration.Kind), migration.From, migration.To))
+}
+
+// abortMigration stops the rename of a child whose name was reverted in the
+// spec or whose syrax is being deleted, and deletes the child created under
+// the new name.
+func (r *SyraxReconciler) abortMigration(ctx context.Context, syrax *syraxv2.Syrax, kind, current string, obj client.Object) error {
+	migration := findMigration(syrax, kind)
+	if migration == nil || (migration.Phase != syraxv2.MigrationPhaseProgressing && migration.Phase != syraxv2.MigrationPhasePending) {
+		return nil
+	}
+	// The old child was deleted by hand and the new one took over early.
+	if migration.To == current {
+		r.completeMigration(syrax, migration)
+		return nil
+	}
+	if err := r.deleteMigrationTarget(ctx, syrax, migration, obj); err != nil {
+		return err
+	}
+	migration.Phase = syraxv2.MigrationPhaseAborted
+	return nil
+}
+
+func (r *SyraxReconciler) deleteMigrationTarget(ctx context.Context, syrax *syraxv2.Syrax, migration *syraxv2.NameMigration, obj client.Object) error {
+	if migration.To == "" {
+		return nil
+	}
+	obj.SetName(migration.To)
+	obj.SetNamespace(syrax.Namespace)
+	return r.deleteChild(ctx, obj)
+}
+
+func hasNodePorts(syrax *syraxv2.Syrax) bool {
+	for _, port := range syrax.Spec.ServiceSpec.Ports {
+		if port.NodePort != nil {
+			return true
+		}
+	}
+	return false
+}
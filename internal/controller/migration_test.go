@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+)
+
+var _ = Describe("Syrax child renames", func() {
+	const resourceName = "migration-resource"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+	named := func(name string) types.NamespacedName {
+		return types.NamespacedName{Name: name, Namespace: "default"}
+	}
+	childName := func(specName string) string {
+		syrax := &targaryenv2.Syrax{ObjectMeta: metav1.ObjectMeta{Name: resourceName}}
+		return childBaseName(syrax, specName) + "-0"
+	}
+
+	var controllerReconciler *SyraxReconciler
+
+	BeforeEach(func() {
+		resource := newSyrax(typeNamespacedName)
+		resource.Spec.DeploymentSpec.Name = "web"
+		resource.Spec.DeploymentSpec.Replicas = ptr.To[int32](2)
+		resource.Spec.ServiceSpec.Name = "web"
+		resource.Spec.ServiceSpec.ServiceType = corev1.ServiceTypeClusterIP
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+		controllerReconciler = newReconciler()
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(k8sClient.Get(ctx, named(childName("web")), &appsv1.Deployment{})).To(Succeed())
+		Expect(k8sClient.Get(ctx, named(childName("web")), &corev1.Service{})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+		deleteOwnedChildren(ctx, typeNamespacedName)
+	})
+
+	rename := func(name string) {
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Spec.DeploymentSpec.Name = name
+		syrax.Spec.ServiceSpec.Name = name
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+	}
+
+	It("should switch to the renamed children once they are ready", func() {
+		rename("api")
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		By("keeping the old deployment until the new one is available")
+		Expect(k8sClient.Get(ctx, named(childName("web")), &appsv1.Deployment{})).To(Succeed())
+		renamed := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, named(childName("api")), renamed)).To(Succeed())
+
+		By("replacing the service right away")
+		err := k8sClient.Get(ctx, named(childName("web")), &corev1.Service{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(k8sClient.Get(ctx, named(childName("api")), &corev1.Service{})).To(Succeed())
+
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		migration := findMigration(syrax, kindDeployment)
+		Expect(migration).NotTo(BeNil())
+		Expect(migration.Phase).To(Equal(targaryenv2.MigrationPhaseProgressing))
+		Expect(migration.From).To(Equal(childName("web")))
+		Expect(migration.To).To(Equal(childName("api")))
+		Expect(findMigration(syrax, kindService).Phase).To(Equal(targaryenv2.MigrationPhaseCompleted))
+
+		By("rolling the new deployment out")
+		markRolledOut(ctx, renamed)
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		err = k8sClient.Get(ctx, named(childName("web")), &appsv1.Deployment{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(ownedDeployment(ctx, typeNamespacedName).Name).To(Equal(childName("api")))
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		migration = findMigration(syrax, kindDeployment)
+		Expect(migration.Phase).To(Equal(targaryenv2.MigrationPhaseCompleted))
+		Expect(migration.CompletedAt).NotTo(BeNil())
+	})
+
+	It("should drop the renamed deployment when the name is reverted", func() {
+		rename("api")
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(k8sClient.Get(ctx, named(childName("api")), &appsv1.Deployment{})).To(Succeed())
+
+		rename("web")
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		err := k8sClient.Get(ctx, named(childName("api")), &appsv1.Deployment{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(ownedDeployment(ctx, typeNamespacedName).Name).To(Equal(childName("web")))
+
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(findMigration(syrax, kindDeployment).Phase).To(Equal(targaryenv2.MigrationPhaseAborted))
+	})
+})
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	namespcedname "k8s.io/apimachinery/pkg/types"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// pausedReason returns the reason and message of why the syrax is paused, or
+// empty strings when its children may be reconciled.
+func (r *SyraxReconciler) pausedReason(syrax *syraxv2.Syrax) (string, string) {
+	switch {
+	case r.PauseAll:
+		return "ClusterPaused", "every syrax is paused by the controller manager"
+	case syrax.Spec.Paused:
+		return "SpecPaused", "the syrax is paused by its spec"
+	}
+	for _, annotation := range []string{utils.PausedAnnotation, utils.PausedAnnotationAlias} {
+		if syrax.Annotations[annotation] == "true" {
+			return "AnnotationPaused", "the syrax is paused by the " + annotation + " annotation"
+		}
+	}
+	return "", ""
+}
+
+// reconcilePaused only reports the status of the existing children of a
+// paused syrax, so that they can be edited by hand without being reverted.
+func (r *SyraxReconciler) reconcilePaused(ctx context.Context, syrax *syraxv2.Syrax, deploymentName, serviceName, reason, message string) error {
+	deployment := &appsv1.Deployment{}
+	err := r.Get(ctx, namespcedname.NamespacedName{Namespace: syrax.Namespace, Name: deploymentName}, deployment)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	service := &corev1.Service{}
+	err = r.Get(ctx, namespcedname.NamespacedName{Namespace: syrax.Namespace, Name: serviceName}, service)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	meta.SetStatusCondition(&syrax.Status.Conditions, metav1.Condition{
+		Type:               syraxv2.ConditionPaused,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: syrax.Generation,
+	})
+	return r.updateSyraxStatus(ctx, syrax, r.servingDeployment(ctx, syrax, deployment), service)
+}
+
+// setResumed flips the Paused condition of a syrax that was paused before.
+func setResumed(syrax *syraxv2.Syrax) {
+	if meta.FindStatusCondition(syrax.Status.Conditions, syraxv2.ConditionPaused) == nil {
+		return
+	}
+	meta.SetStatusCondition(&syrax.Status.Conditions, metav1.Condition{
+		Type:               syraxv2.ConditionPaused,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Resumed",
+		Message:            "the children of the syrax are reconciled",
+		ObservedGeneration: syrax.Generation,
+	})
+}
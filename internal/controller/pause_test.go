@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+)
+
+var _ = Describe("Paused Syrax", func() {
+	const resourceName = "paused-resource"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+
+	BeforeEach(func() {
+		resource := newSyrax(typeNamespacedName)
+		resource.Spec.DeploymentSpec.Replicas = ptr.To[int32](1)
+		resource.Spec.Paused = true
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+	})
+
+	It("should leave the children untouched and report the Paused condition", func() {
+		controllerReconciler := newReconciler()
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		deployments := &appsv1.DeploymentList{}
+		Expect(k8sClient.List(ctx, deployments, client.InNamespace("default"))).To(Succeed())
+		for i := range deployments.Items {
+			owner := metav1.GetControllerOf(&deployments.Items[i])
+			Expect(owner == nil || owner.Name != resourceName).To(BeTrue())
+		}
+
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		condition := meta.FindStatusCondition(syrax.Status.Conditions, targaryenv2.ConditionPaused)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal("SpecPaused"))
+	})
+
+	It("should accept both paused annotations", func() {
+		controllerReconciler := &SyraxReconciler{}
+		for _, annotation := range []string{utils.PausedAnnotation, utils.PausedAnnotationAlias} {
+			syrax := &targaryenv2.Syrax{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{annotation: "true"},
+			}}
+			reason, _ := controllerReconciler.pausedReason(syrax)
+			Expect(reason).To(Equal("AnnotationPaused"))
+		}
+	})
+
+	It("should remove the finalizer of a paused syrax being deleted", func() {
+		controllerReconciler := newReconciler()
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Spec.DeletionPolicy = "Delete"
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(syrax.Finalizers).To(ContainElement(utils.DefaultFinalizer))
+
+		Expect(k8sClient.Delete(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, syrax))).To(BeTrue())
+	})
+})
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// checkPolicies sets the PolicyViolation condition of the syrax from the
+// policies of its namespace. The webhook rejects new violations, so this only
+// reports the syraxes that predate a policy or get a violating spec from
+// their template; their children are still reconciled.
+func (r *SyraxReconciler) checkPolicies(ctx context.Context, syrax *syraxv2.Syrax) error {
+	policies := &syraxv2.SyraxPolicyList{}
+	if err := r.List(ctx, policies, client.InNamespace(syrax.Namespace)); err != nil {
+		return err
+	}
+	if len(policies.Items) == 0 {
+		meta.RemoveStatusCondition(&syrax.Status.Conditions, syraxv2.ConditionPolicyViolation)
+		return nil
+	}
+
+	var violations []string
+	for i := range policies.Items {
+		for _, err := range policies.Items[i].Violations(syrax) {
+			violations = append(violations, err.Error())
+		}
+	}
+	condition := metav1.Condition{
+		Type:               syraxv2.ConditionPolicyViolation,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Compliant",
+		Message:            fmt.Sprintf("the syrax complies with the %d syraxpolicies of its namespace", len(policies.Items)),
+		ObservedGeneration: syrax.Generation,
+	}
+	if len(violations) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Violated"
+		condition.Message = strings.Join(violations, "; ")
+	}
+	meta.SetStatusCondition(&syrax.Status.Conditions, condition)
+	return nil
+}
+
+// syraxesForPolicy returns a request for every syrax of the namespace of the
+// policy, so that their PolicyViolation condition follows the policy.
+func (r *SyraxReconciler) syraxesForPolicy(ctx context.Context, policy client.Object) []reconcile.Request {
+	syraxes := &syraxv2.SyraxList{}
+	if err := r.List(ctx, syraxes, client.InNamespace(policy.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Unable to list the syraxes of policy", "policy", policy.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(syraxes.Items))
+	for i := range syraxes.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&syraxes.Items[i])})
+	}
+	return requests
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+)
+
+var _ = Describe("Syrax policies", func() {
+	const resourceName = "policy-resource"
+	const policyName = "restricted"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+
+	var controllerReconciler *SyraxReconciler
+
+	compliantSyrax := func() *targaryenv2.Syrax {
+		syrax := newSyrax(typeNamespacedName)
+		syrax.Spec.Labels = map[string]string{"team": "payments"}
+		syrax.Spec.DeploymentSpec.Image = "registry.example.com/shop/api:1.0"
+		syrax.Spec.DeploymentSpec.Replicas = ptr.To[int32](2)
+		syrax.Spec.DeploymentSpec.Resources = &corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+		}
+		syrax.Spec.ServiceSpec.ServiceType = corev1.ServiceTypeClusterIP
+		return syrax
+	}
+
+	BeforeEach(func() {
+		policy := &targaryenv2.SyraxPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: policyName, Namespace: "default"},
+			Spec: targaryenv2.SyraxPolicySpec{
+				AllowedRegistries:   []string{"registry.example.com"},
+				MaxReplicas:         ptr.To[int32](3),
+				AllowedServiceTypes: []corev1.ServiceType{corev1.ServiceTypeClusterIP},
+				RequiredLabels:      []string{"team"},
+				MaxResources:        corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+
+		controllerReconciler = newReconciler()
+	})
+
+	AfterEach(func() {
+		policy := &targaryenv2.SyraxPolicy{ObjectMeta: metav1.ObjectMeta{Name: policyName, Namespace: "default"}}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, policy))).To(Succeed())
+
+		deleteSyrax(ctx, typeNamespacedName)
+		deleteOwnedChildren(ctx, typeNamespacedName)
+	})
+
+	It("should report the violations of an existing syrax in its status", func() {
+		policy := &targaryenv2.SyraxPolicy{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: policyName, Namespace: "default"}, policy)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, policy)).To(Succeed())
+
+		syrax := compliantSyrax()
+		syrax.Spec.DeploymentSpec.Replicas = ptr.To[int32](5)
+		Expect(k8sClient.Create(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(meta.FindStatusCondition(syrax.Status.Conditions, targaryenv2.ConditionPolicyViolation)).To(BeNil())
+
+		By("creating the policy after the syrax")
+		policy.ResourceVersion = ""
+		Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		condition := meta.FindStatusCondition(syrax.Status.Conditions, targaryenv2.ConditionPolicyViolation)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Message).To(ContainSubstring("spec.deploymentSpec.replicas"))
+
+		By("still reconciling the children of the syrax")
+		deployment := ownedDeployment(ctx, typeNamespacedName)
+		Expect(*deployment.Spec.Replicas).To(Equal(int32(5)))
+
+		By("clearing the violation once the spec complies")
+		syrax.Spec.DeploymentSpec.Replicas = ptr.To[int32](3)
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(meta.IsStatusConditionFalse(syrax.Status.Conditions, targaryenv2.ConditionPolicyViolation)).To(BeTrue())
+	})
+})
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+)
+
+var _ = Describe("Generated RBAC", func() {
+	const resourceName = "rbac-resource"
+	const serviceAccountName = "syrax-controller-rbac-test"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+
+	var role *rbacv1.ClusterRole
+	var binding *rbacv1.ClusterRoleBinding
+	var serviceAccount *corev1.ServiceAccount
+
+	BeforeEach(func() {
+		By("installing the generated role bound to a service account")
+		raw, err := os.ReadFile(filepath.Join("..", "..", "config", "rbac", "role.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		role = &rbacv1.ClusterRole{}
+		Expect(yaml.Unmarshal(raw, role)).To(Succeed())
+		role.Name = serviceAccountName
+		Expect(k8sClient.Create(ctx, role)).To(Succeed())
+
+		serviceAccount = &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: serviceAccountName, Namespace: "default"},
+		}
+		Expect(k8sClient.Create(ctx, serviceAccount)).To(Succeed())
+
+		binding = &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: serviceAccountName},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "ClusterRole",
+				Name:     role.Name,
+			},
+			Subjects: []rbacv1.Subject{{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      serviceAccount.Name,
+				Namespace: serviceAccount.Namespace,
+			}},
+		}
+		Expect(k8sClient.Create(ctx, binding)).To(Succeed())
+
+		resource := newSyrax(typeNamespacedName)
+		resource.Spec.DeletionPolicy = "Delete"
+		resource.Spec.DeploymentSpec.Replicas = ptr.To[int32](1)
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+
+		Expect(k8sClient.Delete(ctx, binding)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, serviceAccount)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, role)).To(Succeed())
+	})
+
+	It("should let the controller reconcile as the service account", func() {
+		impersonated := rest.CopyConfig(cfg)
+		impersonated.Impersonate = rest.ImpersonationConfig{
+			UserName: "system:serviceaccount:" + serviceAccount.Namespace + ":" + serviceAccount.Name,
+		}
+		serviceAccountClient, err := client.New(impersonated, client.Options{Scheme: k8sClient.Scheme()})
+		Expect(err).NotTo(HaveOccurred())
+
+		controllerReconciler := newReconciler()
+		controllerReconciler.Client = serviceAccountClient
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		deployment := ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:1.25"))
+
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(syrax.Finalizers).NotTo(BeEmpty())
+		Expect(syrax.Status.AvailableReplicas).NotTo(BeNil())
+
+		By("checking that the role grants nothing beyond what the controller needs")
+		err = serviceAccountClient.Delete(ctx, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: "rbac-forbidden"},
+		})
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,249 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ImageResolver resolves the tag of an image to the digest of the manifest it
+// points to. The pull secrets of the syrax are passed along for private
+// registries.
+type ImageResolver interface {
+	Resolve(ctx context.Context, image string, pullSecrets []corev1.Secret) (string, error)
+}
+
+// manifestMediaTypes are the manifests a tag may point to, image indexes
+// first so that multi-arch images resolve to the digest of their index.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// RegistryResolver resolves images with the HTTP API of their registry. It
+// authenticates with the basic and bearer token schemes, anonymously or with
+// the credentials of the pull secrets for the registry.
+type RegistryResolver struct {
+	// Client sends the requests, a client timing out after 30 seconds is
+	// used when nil.
+	Client *http.Client
+}
+
+var _ ImageResolver = &RegistryResolver{}
+
+// defaultRegistryClient bounds the requests to the registries, so that an
+// unresponsive one does not hold up the reconcile of the syrax.
+var defaultRegistryClient = &http.Client{Timeout: 30 * time.Second}
+
+// Resolve returns the digest of the manifest the tag of the image points to.
+func (r *RegistryResolver) Resolve(ctx context.Context, image string, pullSecrets []corev1.Secret) (string, error) {
+	registry, repository, tag := parseImage(image)
+	host := registry
+	if host == "docker.io" {
+		host = "registry-1.docker.io"
+	}
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, repository, tag)
+	username, password := registryCredentials(registry, pullSecrets)
+
+	resp, err := r.head(ctx, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		authorization, err := r.authorize(ctx, resp.Header.Get("WWW-Authenticate"), username, password)
+		if err != nil {
+			return "", fmt.Errorf("unable to authenticate to %s: %w", registry, err)
+		}
+		if resp, err = r.head(ctx, manifestURL, authorization); err != nil {
+			return "", err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to resolve %s: %s responded %s", image, registry, resp.Status)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("unable to resolve %s: %s did not return a digest", image, registry)
+	}
+	return digest, nil
+}
+
+func (r *RegistryResolver) client() *http.Client {
+	if r.Client != nil {
+		return r.Client
+	}
+	return defaultRegistryClient
+}
+
+func (r *RegistryResolver) head(ctx context.Context, manifestURL, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// authorize returns the Authorization header answering the challenge of the
+// registry.
+func (r *RegistryResolver) authorize(ctx context.Context, challenge, username, password string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch scheme {
+	case "basic":
+		if username == "" {
+			return "", fmt.Errorf("no credentials for basic authentication")
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("unsupported challenge %q", challenge)
+	}
+
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil || tokenURL.Host == "" {
+		return "", fmt.Errorf("invalid realm %q", params["realm"])
+	}
+	query := tokenURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint responded %s", resp.Status)
+	}
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("invalid token response: %w", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	return "Bearer " + token.Token, nil
+}
+
+// parseChallenge splits a WWW-Authenticate header into its lower-cased
+// scheme and its parameters.
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return strings.ToLower(scheme), params
+}
+
+// parseImage splits an image into its registry, repository and tag, with the
+// defaults of docker: docker.io, library/ and latest.
+func parseImage(image string) (string, string, string) {
+	registry := "docker.io"
+	if host, rest, found := strings.Cut(image, "/"); found && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		registry, image = host, rest
+	}
+	tag := "latest"
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image, tag = image[:i], image[i+1:]
+	}
+	if registry == "docker.io" && !strings.Contains(image, "/") {
+		image = "library/" + image
+	}
+	return registry, image, tag
+}
+
+// dockerConfig is the content of a kubernetes.io/dockerconfigjson secret, or
+// the auths alone for a kubernetes.io/dockercfg one.
+type dockerConfig struct {
+	Auths map[string]dockerAuth `json:"auths"`
+}
+
+type dockerAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Auth     string `json:"auth"`
+}
+
+// registryCredentials returns the username and password the pull secrets
+// hold for the registry, if any.
+func registryCredentials(registry string, pullSecrets []corev1.Secret) (string, string) {
+	for _, secret := range pullSecrets {
+		config := dockerConfig{}
+		switch secret.Type {
+		case corev1.SecretTypeDockerConfigJson:
+			if json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config) != nil {
+				continue
+			}
+		case corev1.SecretTypeDockercfg:
+			if json.Unmarshal(secret.Data[corev1.DockerConfigKey], &config.Auths) != nil {
+				continue
+			}
+		default:
+			continue
+		}
+		for server, auth := range config.Auths {
+			if registryHost(server) != registryHost(registry) {
+				continue
+			}
+			if auth.Username == "" && auth.Auth != "" {
+				decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+				if err != nil {
+					continue
+				}
+				auth.Username, auth.Password, _ = strings.Cut(string(decoded), ":")
+			}
+			return auth.Username, auth.Password
+		}
+	}
+	return "", ""
+}
+
+// registryHost returns the host of a server of a docker config, which may be
+// a URL, with the aliases of docker hub folded into docker.io.
+func registryHost(server string) string {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	server, _, _ = strings.Cut(server, "/")
+	switch server {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return server
+}
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+)
+
+// restartedAt returns the restart annotation of the pods of the syrax, or an
+// empty string when it was never restarted.
+func restartedAt(syrax *syraxv2.Syrax) string {
+	if syrax.Spec.RestartAt == nil {
+		return ""
+	}
+	return syrax.Spec.RestartAt.UTC().Format(time.RFC3339)
+}
+
+// setRestart stamps the restartAt of the syrax on the pod template, so that
+// changing it rolls the pods like kubectl rollout restart does.
+func setRestart(syrax *syraxv2.Syrax, template *corev1.PodTemplateSpec) {
+	value := restartedAt(syrax)
+	if value == "" {
+		return
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[utils.RestartedAtAnnotation] = value
+}
+
+// restartUpdated reports whether the pods of the deployment were not started
+// for the restartAt of the syrax. Clearing restartAt does not restart them.
+func restartUpdated(syrax *syraxv2.Syrax, deployment *appsv1.Deployment) bool {
+	value := restartedAt(syrax)
+	return value != "" && deployment.Spec.Template.Annotations[utils.RestartedAtAnnotation] != value
+}
+
+// setRestartStatus records the restart the pod template of the deployment
+// was last stamped with.
+func setRestartStatus(syrax *syraxv2.Syrax, deployment *appsv1.Deployment) {
+	value, ok := deployment.Spec.Template.Annotations[utils.RestartedAtAnnotation]
+	if !ok {
+		return
+	}
+	restartAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return
+	}
+	syrax.Status.LastRestartAt = &metav1.Time{Time: restartAt}
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+)
+
+var _ = Describe("Syrax restarts", func() {
+	const resourceName = "restart-resource"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+
+	var controllerReconciler *SyraxReconciler
+
+	BeforeEach(func() {
+		resource := newSyrax(typeNamespacedName)
+		resource.Spec.DeploymentSpec.Image = "shop/api:1.0"
+		resource.Spec.ServiceSpec.ServiceType = corev1.ServiceTypeClusterIP
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+		controllerReconciler = newReconciler()
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+		deleteOwnedChildren(ctx, typeNamespacedName)
+	})
+
+	It("should restart the pods when restartAt changes", func() {
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		deployment := ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Annotations).NotTo(HaveKey(utils.RestartedAtAnnotation))
+
+		restartAt := metav1.NewTime(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Spec.RestartAt = &restartAt
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		deployment = ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue(utils.RestartedAtAnnotation, "2024-05-01T12:00:00Z"))
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(syrax.Status.LastRestartAt).NotTo(BeNil())
+		Expect(syrax.Status.LastRestartAt.Equal(&restartAt)).To(BeTrue())
+
+		By("reverting manual changes to the restart annotation")
+		deployment.Spec.Template.Annotations[utils.RestartedAtAnnotation] = "2024-05-02T00:00:00Z"
+		Expect(k8sClient.Update(ctx, deployment)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		deployment = ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue(utils.RestartedAtAnnotation, "2024-05-01T12:00:00Z"))
+
+		By("leaving the pods alone when restartAt is cleared")
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Spec.RestartAt = nil
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		deployment = ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue(utils.RestartedAtAnnotation, "2024-05-01T12:00:00Z"))
+	})
+})
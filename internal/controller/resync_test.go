@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+)
+
+var _ = Describe("Syrax resync", func() {
+	const resourceName = "resync-resource"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+
+	BeforeEach(func() {
+		resource := newSyrax(typeNamespacedName)
+		resource.Spec.DeploymentSpec.Replicas = ptr.To[int32](2)
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+	})
+
+	It("should requeue the syrax with jitter until it is Ready", func() {
+		controllerReconciler := newReconciler()
+		controllerReconciler.NotReadyResyncPeriod = time.Minute
+		result := reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(result.RequeueAfter).To(BeNumerically(">=", time.Minute))
+		Expect(result.RequeueAfter).To(BeNumerically("<=", time.Minute+time.Minute/10))
+
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		condition := meta.FindStatusCondition(syrax.Status.Conditions, targaryenv2.ConditionReady)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("ReplicasUnavailable"))
+
+		By("Rolling the deployment out")
+		markRolledOut(ctx, ownedDeployment(ctx, typeNamespacedName))
+
+		result = reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(result.RequeueAfter).To(BeZero())
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(syrax.Status.Conditions, targaryenv2.ConditionReady)).To(BeTrue())
+	})
+})
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const reasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+
+// reconcileRollback records the pod template of every completed rollout on the
+// deployment and, when the syrax opted in, restores it once a later rollout
+// exceeds its progress deadline.
+func (r *SyraxReconciler) reconcileRollback(ctx context.Context, syrax *syraxv2.Syrax, deployment *appsv1.Deployment) error {
+	if syrax.Status.RolledBackGeneration != 0 && syrax.Status.RolledBackGeneration != syrax.Generation &&
+		meta.IsStatusConditionTrue(syrax.Status.Conditions, syraxv2.ConditionRolledBack) {
+		meta.SetStatusCondition(&syrax.Status.Conditions, metav1.Condition{
+			Type:               syraxv2.ConditionRolledBack,
+			Status:             metav1.ConditionFalse,
+			Reason:             "SpecChanged",
+			Message:            "the spec changed after the last rollback and is being rolled out",
+			ObservedGeneration: syrax.Generation,
+		})
+	}
+
+	if rolloutComplete(deployment) {
+		return r.recordGoodTemplate(ctx, deployment)
+	}
+	if syrax.Spec.Rollback == nil || !syrax.Spec.Rollback.OnFailure || !rolloutFailed(deployment) {
+		return nil
+	}
+	if syrax.Status.RolledBackGeneration == syrax.Generation || syrax.DeletionTimestamp != nil {
+		return nil
+	}
+
+	raw, ok := deployment.Annotations[utils.LastGoodTemplateAnnotation]
+	if !ok {
+		return nil
+	}
+	template := corev1.PodTemplateSpec{}
+	if err := json.Unmarshal([]byte(raw), &template); err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(template, deployment.Spec.Template) {
+		return nil
+	}
+
+	failedImage := containerImage(deployment)
+	deployment.Spec.Template = template
+	if err := r.updateChild(ctx, deployment); err != nil {
+		return err
+	}
+
+	// the failed template must not be applied again by a later reconcile, even
+	// if the status update at the end of this one fails.
+	message := fmt.Sprintf("rollout of image %s exceeded its progress deadline, rolled back to image %s", failedImage, containerImage(deployment))
+	if err := r.patchSyraxStatus(ctx, syrax, func(syrax *syraxv2.Syrax) {
+		syrax.Status.RolledBackGeneration = syrax.Generation
+		meta.SetStatusCondition(&syrax.Status.Conditions, metav1.Condition{
+			Type:               syraxv2.ConditionRolledBack,
+			Status:             metav1.ConditionTrue,
+			Reason:             reasonProgressDeadlineExceeded,
+			Message:            message,
+			ObservedGeneration: syrax.Generation,
+		})
+	}); err != nil {
+		return err
+	}
+	r.warningEvent(syrax, ReasonRolledBack, message)
+	log.FromContext(ctx).Info("Rolled back deployment", "failedImage", failedImage, "image", containerImage(deployment))
+	return nil
+}
+
+// recordGoodTemplate stores the current pod template of the deployment as the
+// last known-good one.
+func (r *SyraxReconciler) recordGoodTemplate(ctx context.Context, deployment *appsv1.Deployment) error {
+	raw, err := json.Marshal(deployment.Spec.Template)
+	if err != nil {
+		return err
+	}
+	if deployment.Annotations[utils.LastGoodTemplateAnnotation] == string(raw) {
+		return nil
+	}
+	if deployment.Annotations == nil {
+		deployment.Annotations = map[string]string{}
+	}
+	deployment.Annotations[utils.LastGoodTemplateAnnotation] = string(raw)
+	return r.updateChild(ctx, deployment)
+}
+
+// rolloutBlocked reports whether the current spec is the one that was rolled
+// back, in which case it must not be applied to the deployment again.
+func rolloutBlocked(syrax *syraxv2.Syrax) bool {
+	return syrax.Spec.Rollback != nil && syrax.Spec.Rollback.OnFailure &&
+		syrax.DeletionTimestamp == nil &&
+		syrax.Status.RolledBackGeneration == syrax.Generation
+}
+
+func rolloutFailed(deployment *appsv1.Deployment) bool {
+	progressing := deploymentCondition(deployment, appsv1.DeploymentProgressing)
+	return progressing != nil && progressing.Status == corev1.ConditionFalse &&
+		progressing.Reason == reasonProgressDeadlineExceeded
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+)
+
+var _ = Describe("Syrax rollback", func() {
+	const resourceName = "rollback-resource"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+
+	var controllerReconciler *SyraxReconciler
+
+	BeforeEach(func() {
+		controllerReconciler = newReconciler()
+		resource := newSyrax(typeNamespacedName)
+		resource.Spec.DeploymentSpec.Replicas = ptr.To[int32](1)
+		resource.Spec.Rollback = &targaryenv2.RollbackSpec{OnFailure: true}
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+	})
+
+	It("should restore the last known-good template when a rollout fails", func() {
+		By("completing the first rollout")
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		deployment := ownedDeployment(ctx, typeNamespacedName)
+		markRolledOut(ctx, deployment)
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		deployment = ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Annotations).To(HaveKey(utils.LastGoodTemplateAnnotation))
+
+		By("pushing a bad image")
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Spec.DeploymentSpec.Image = "nginx:does-not-exist"
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		deployment = ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:does-not-exist"))
+
+		By("exceeding the progress deadline")
+		deployment.Status.ObservedGeneration = deployment.Generation
+		deployment.Status.UpdatedReplicas = 0
+		deployment.Status.Conditions = []appsv1.DeploymentCondition{{
+			Type:   appsv1.DeploymentProgressing,
+			Status: corev1.ConditionFalse,
+			Reason: reasonProgressDeadlineExceeded,
+		}}
+		Expect(k8sClient.Status().Update(ctx, deployment)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		deployment = ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:1.25"))
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(syrax.Status.Conditions, targaryenv2.ConditionRolledBack)).To(BeTrue())
+		Expect(syrax.Status.RolledBackGeneration).To(Equal(syrax.Generation))
+
+		By("refusing to re-apply the failing spec")
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		deployment = ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:1.25"))
+	})
+})
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// resyncJitterFactor spreads the resync of a syrax over up to 10% of the period.
+const resyncJitterFactor = 0.1
+
+// setRolloutStatus copies the rollout progress of the deployment into the
+// syrax status and mirrors the deployment's Progressing condition.
+func setRolloutStatus(syrax *syraxv2.Syrax, deployment *appsv1.Deployment) {
+	syrax.Status.UpdatedReplicas = deployment.Status.UpdatedReplicas
+	syrax.Status.ReadyReplicas = deployment.Status.ReadyReplicas
+	syrax.Status.TargetImage = syrax.Spec.DeploymentSpec.Image
+	if image := containerImage(deployment); image != "" && rolloutComplete(deployment) {
+		syrax.Status.CurrentImage = image
+	}
+
+	condition := metav1.Condition{
+		Type:               syraxv2.ConditionProgressing,
+		Status:             metav1.ConditionUnknown,
+		Reason:             "DeploymentPending",
+		Message:            "the deployment has not reported its progress yet",
+		ObservedGeneration: syrax.Generation,
+	}
+	if progressing := deploymentCondition(deployment, appsv1.DeploymentProgressing); progressing != nil {
+		condition.Status = metav1.ConditionStatus(progressing.Status)
+		condition.Message = progressing.Message
+		if progressing.Reason != "" {
+			condition.Reason = progressing.Reason
+		}
+	}
+	meta.SetStatusCondition(&syrax.Status.Conditions, condition)
+}
+
+// setReadyCondition reports whether the syrax serves every desired replica
+// through a reachable service, and so do its components.
+func setReadyCondition(syrax *syraxv2.Syrax, deployment *appsv1.Deployment, service *corev1.Service) {
+	condition := metav1.Condition{
+		Type:               syraxv2.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Available",
+		Message:            "every replica is available and the service is reachable",
+		ObservedGeneration: syrax.Generation,
+	}
+	reason, message := unavailableReason(desiredReplicas(syrax), deployment, service)
+	if reason == "" {
+		for _, component := range syrax.Status.Components {
+			if !component.Ready {
+				reason = "ComponentUnavailable"
+				message = fmt.Sprintf("component %s: %s", component.Name, component.Message)
+				break
+			}
+		}
+	}
+	if reason != "" {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reason
+		condition.Message = message
+	}
+	meta.SetStatusCondition(&syrax.Status.Conditions, condition)
+}
+
+// unavailableReason returns why the deployment and the service do not serve
+// the desired replicas yet, or empty strings when they do. The service may
+// be nil.
+func unavailableReason(desired int32, deployment *appsv1.Deployment, service *corev1.Service) (string, string) {
+	switch {
+	case deployment.Status.AvailableReplicas < desired || deployment.Status.UpdatedReplicas < desired:
+		return "ReplicasUnavailable", fmt.Sprintf("%d of %d replicas are available", deployment.Status.AvailableReplicas, desired)
+	case service != nil && service.Spec.Type == corev1.ServiceTypeLoadBalancer && len(service.Status.LoadBalancer.Ingress) == 0:
+		return "LoadBalancerPending", fmt.Sprintf("service %s has no load balancer ingress yet", service.Name)
+	}
+	return "", ""
+}
+
+// requeueIfNotReady resyncs a syrax that is not Ready after the period, with
+// some jitter so that syraxes created together are not resynced together.
+// Results that already requeue right away are left alone, as a RequeueAfter
+// would delay them.
+func requeueIfNotReady(result ctrl.Result, syrax *syraxv2.Syrax, period time.Duration) ctrl.Result {
+	if period <= 0 || syrax.DeletionTimestamp != nil || requeuesNow(result) ||
+		meta.IsStatusConditionTrue(syrax.Status.Conditions, syraxv2.ConditionReady) {
+		return result
+	}
+	requeueAfter := wait.Jitter(period, resyncJitterFactor)
+	if result.RequeueAfter == 0 || requeueAfter < result.RequeueAfter {
+		result.RequeueAfter = requeueAfter
+	}
+	return result
+}
+
+// requeuesNow reports whether the result requeues the syrax without delay.
+func requeuesNow(result ctrl.Result) bool {
+	return result.Requeue && result.RequeueAfter == 0
+}
+
+// rolloutComplete reports whether every replica of the deployment runs the
+// latest pod template and is available.
+func rolloutComplete(deployment *appsv1.Deployment) bool {
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	return deployment.Status.UpdatedReplicas == replicas &&
+		deployment.Status.Replicas == replicas &&
+		deployment.Status.AvailableReplicas == replicas
+}
+
+func deploymentCondition(deployment *appsv1.Deployment, conditionType appsv1.DeploymentConditionType) *appsv1.DeploymentCondition {
+	for i := range deployment.Status.Conditions {
+		if deployment.Status.Conditions[i].Type == conditionType {
+			return &deployment.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func containerImage(deployment *appsv1.Deployment) string {
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name == utils.ContainerName {
+			return container.Image
+		}
+	}
+	return ""
+}
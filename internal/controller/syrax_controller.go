@@ -25,17 +25,22 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	namespcedname "k8s.io/apimachinery/pkg/types"
-	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/record"
-	syraxv1 "resource.controller.sigs/resource-controller-k8s-sigs/api/v1"
-	targaryenv1 "resource.controller.sigs/resource-controller-k8s-sigs/api/v1"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
 	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"time"
 )
 
@@ -44,16 +49,42 @@ type SyraxReconciler struct {
 	client.Client
 	SubRCClient client.SubResourceClient
 	Cache       cache.Cache
-	Scheme      *runtime.Scheme
-	Recorder    record.EventRecorder
+	// APIReader reads objects that the cache may not hold, such as the
+	// unmanaged objects whose names the children must not take. The client
+	// is used when it is nil.
+	APIReader client.Reader
+	Scheme    *runtime.Scheme
+	Recorder  record.EventRecorder
+	// PauseAll stops the reconciliation of the children of every syrax.
+	PauseAll bool
+	// Tracer records the spans of every reconcile, the global tracer
+	// provider is used when it is nil.
+	Tracer trace.Tracer
+	// RateLimiter delays the retries of failed reconciles, the default
+	// controller rate limiter is used when it is nil.
+	RateLimiter ratelimiter.RateLimiter
+	// NotReadyResyncPeriod requeues the syraxes that are not Ready, so that
+	// their status converges without watch events. Zero disables it.
+	NotReadyResyncPeriod time.Duration
+	// ImageResolver resolves the tags of the images with an image policy, a
+	// RegistryResolver is used when it is nil.
+	ImageResolver ImageResolver
+	// RegistryCredentialsNamespaces lists the namespaces the registry
+	// credentials may be copied from. None may be copied when it is empty.
+	RegistryCredentialsNamespaces []string
 }
 
-//+kubebuilder:rbac:groups=targaryen.resource.controller.sigs,resources=syraxs,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups=targaryen.resource.controller.sigs,resources=syraxs/status,verbs=get;update;patch
-//+kubebuilder:rbac:groups=targaryen.resource.controller.sigs,resources=syraxs/finalizers,verbs=update
-//+kubebuilder:rbac:groups=targaryen.resource.controller.sigs,resources=deployments,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups=targaryen.resource.controller.sigs,resources=deployments/status,verbs=get
-//+kubebuilder:rbac:groups=targaryen.resource.controller.sigs,resources=services,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=targaryen.resource.controller.sigs,resources=syraxes,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=targaryen.resource.controller.sigs,resources=syraxes/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=targaryen.resource.controller.sigs,resources=syraxes/finalizers,verbs=update
+//+kubebuilder:rbac:groups=targaryen.resource.controller.sigs,resources=syraxtemplates,verbs=get;list;watch
+//+kubebuilder:rbac:groups=targaryen.resource.controller.sigs,resources=syraxpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;create;update
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -65,103 +96,263 @@ type SyraxReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.17.0/pkg/reconcile
 func (r *SyraxReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	ctx, span := r.startSpan(ctx, "Reconcile", syraxAttributes(req.NamespacedName)...)
+	result, err := r.reconcile(ctx, req)
+	endSpan(span, err)
+	return result, err
+}
 
-	fmt.Println("Reconcile started")
+func (r *SyraxReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	// The controller already tags the context logger with the namespace, name
+	// and reconcileID of the request.
+	logger := log.FromContext(ctx)
+	logger.V(1).Info("Reconcile started")
 
-	syrax := &syraxv1.Syrax{}
-	err := r.Get(context.TODO(), req.NamespacedName, syrax)
+	syrax := &syraxv2.Syrax{}
+	err := r.Get(ctx, req.NamespacedName, syrax)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			utilruntime.HandleError(fmt.Errorf("syrax '%s' in work queue no longer exists", req.NamespacedName))
+			logger.V(1).Info("Syrax no longer exists")
+			forgetSyraxMetrics(req.NamespacedName)
+			events.forget(req.NamespacedName)
 			return ctrl.Result{}, nil
 
 		}
-		return ctrl.Result{}, nil
+		logger.Error(err, "Unable to get syrax")
+		return r.handleError(ctx, nil, err)
 	}
+	logger = logger.WithValues("generation", syrax.Generation)
+	ctx = log.IntoContext(ctx, logger)
+	setSyraxGeneration(ctx, syrax)
 
 	if ctrlutil.ContainsFinalizer(syrax, utils.DefaultFinalizer) == false && syrax.Spec.DeletionPolicy == "Delete" {
 		ctrlutil.AddFinalizer(syrax, utils.DefaultFinalizer)
-		err = r.Update(context.TODO(), syrax)
+		logger.V(1).Info("Adding finalizer", "finalizer", utils.DefaultFinalizer)
+		err = r.Update(ctx, syrax)
 	}
 	if err != nil {
-		return ctrl.Result{}, err
+		logger.Error(err, "Unable to add finalizer")
+		return r.handleError(ctx, syrax, err)
 	}
 
+	// a syrax being deleted must not wait for a template that may be gone,
+	// or its finalizer would never be removed.
+	if err = r.applyTemplate(ctx, syrax); err != nil && syrax.DeletionTimestamp != nil {
+		logger.Info("Skipping template of syrax being deleted", "reason", err.Error())
+		err = nil
+	}
+	if err != nil {
+		logger.Error(err, "Unable to resolve template")
+		r.warningEvent(syrax, ReasonTemplateFailed, fmt.Sprintf("unable to resolve template: %v", err))
+		return r.handleError(ctx, syrax, err)
+	}
 	setDefaultFields(syrax)
+	if err = r.checkPolicies(ctx, syrax); err != nil {
+		logger.Error(err, "Unable to check syraxpolicies")
+		return r.handleError(ctx, syrax, err)
+	}
+	imagesRequeueAfter, err := r.resolveImages(ctx, syrax)
+	if err != nil {
+		logger.Error(err, "Unable to resolve images")
+		return r.handleError(ctx, syrax, err)
+	}
 
+	_, span := r.startSpan(ctx, "ResolveNames")
 	deploymentName := r.getDeploymentName(syrax)
 	serviceName := r.getServiceName(syrax)
+	span.SetAttributes(attribute.String("deployment.name", deploymentName), attribute.String("service.name", serviceName))
+	endSpan(span, nil)
+	logger = logger.WithValues("deployment", deploymentName, "service", serviceName)
+	ctx = log.IntoContext(ctx, logger)
+
+	// a syrax being deleted is cleaned up even when paused, or its finalizer
+	// would never be removed.
+	if reason, message := r.pausedReason(syrax); reason != "" && syrax.DeletionTimestamp == nil {
+		logger.V(1).Info("Syrax is paused, only updating status", "reason", reason)
+		return r.handleError(ctx, syrax, r.reconcilePaused(ctx, syrax, deploymentName, serviceName, reason, message))
+	}
+	setResumed(syrax)
+
+	if deploymentName, err = r.migrateDeployment(ctx, syrax, deploymentName); err != nil {
+		logger.Error(err, "Unable to rename deployment")
+		r.warningEvent(syrax, ReasonUpdateFailed, fmt.Sprintf("unable to rename deployment %s: %v", deploymentName, err))
+		return r.handleError(ctx, syrax, err)
+	}
+	if serviceName, err = r.migrateService(ctx, syrax, serviceName); err != nil {
+		logger.Error(err, "Unable to rename service")
+		r.warningEvent(syrax, ReasonUpdateFailed, fmt.Sprintf("unable to rename service %s: %v", serviceName, err))
+		return r.handleError(ctx, syrax, err)
+	}
+
+	if err = r.reconcileRegistryCredentials(ctx, syrax); err != nil {
+		logger.Error(err, "Unable to copy registry credentials")
+		r.warningEvent(syrax, ReasonCredentialsFailed, fmt.Sprintf("unable to copy registry credentials: %v", err))
+		return r.handleError(ctx, syrax, err)
+	}
+	if err = r.reconcileConfigFiles(ctx, syrax); err != nil {
+		logger.Error(err, "Unable to reconcile config files")
+		r.warningEvent(syrax, ReasonUpdateFailed, fmt.Sprintf("unable to write config files to configmap %s: %v", configMapName(syrax), err))
+		return r.handleError(ctx, syrax, err)
+	}
 
 	deployment := &appsv1.Deployment{}
-	if err = r.Get(context.TODO(), namespcedname.NamespacedName{req.Namespace, deploymentName}, deployment); err != nil {
+	if err = r.getChild(ctx, namespcedname.NamespacedName{Namespace: req.Namespace, Name: deploymentName}, deployment); err != nil {
 		r.newDeployment(syrax, deploymentName, deployment)
-		err = r.Create(context.TODO(), deployment)
+		if err = r.createChild(ctx, deployment); err == nil {
+			r.normalEvent(syrax, ReasonCreated, fmt.Sprintf("created deployment %s", deploymentName))
+		}
 	}
 	if err != nil {
-		r.Recorder.Event(syrax, "Warning", err.Error(), fmt.Sprintf("the deployment for syrax kind with name %s is not present and can't be created", syrax.Name))
-		return ctrl.Result{}, nil
+		logger.Error(err, "Unable to create deployment")
+		r.warningEvent(syrax, ReasonCreateFailed, fmt.Sprintf("unable to create deployment %s: %v", deploymentName, err))
+		return r.handleError(ctx, syrax, err)
 	}
 
 	service := &corev1.Service{}
-	if err = r.Get(context.TODO(), namespcedname.NamespacedName{req.Namespace, serviceName}, service); err != nil {
+	if err = r.getChild(ctx, namespcedname.NamespacedName{Namespace: req.Namespace, Name: serviceName}, service); err != nil {
 		service = r.newService(syrax, serviceName, service)
-		err = r.Create(context.TODO(), service)
+		if err = r.createChild(ctx, service); err == nil {
+			r.normalEvent(syrax, ReasonCreated, fmt.Sprintf("created service %s", serviceName))
+		}
 	}
 
 	if err != nil {
-		r.Recorder.Event(syrax, "Warning", err.Error(), fmt.Sprintf("the service for syrax kind with name %s is not present and can't be created", syrax.Name))
-		return ctrl.Result{}, nil
+		logger.Error(err, "Unable to create service")
+		r.warningEvent(syrax, ReasonCreateFailed, fmt.Sprintf("unable to create service %s: %v", serviceName, err))
+		return r.handleError(ctx, syrax, err)
 	}
 
-	if ifDeployUpdated(syrax, deployment) == true {
-		log.Log.Info("Update deployment resource")
+	if err = r.reconcileRollback(ctx, syrax, deployment); err != nil {
+		r.warningEvent(syrax, ReasonRollbackFailed, fmt.Sprintf("unable to roll back deployment %s: %v", deploymentName, err))
+		return r.handleError(ctx, syrax, err)
+	}
+
+	if !canaryEnabled(syrax) {
+		err = r.abortCanary(ctx, syrax, deploymentName)
+	}
+	if err == nil && !blueGreenEnabled(syrax) {
+		err = r.abortBlueGreen(ctx, syrax, deploymentName, serviceName, deployment)
+	}
+
+	var result ctrl.Result
+	switch {
+	case err != nil:
+	case canaryEnabled(syrax):
+		result, err = r.reconcileCanary(ctx, syrax, deploymentName, deployment)
+	case blueGreenEnabled(syrax):
+		result, err = r.reconcileBlueGreen(ctx, syrax, deploymentName, serviceName, deployment)
+	case ifDeployUpdated(syrax, deployment) == true && !rolloutBlocked(syrax):
+		current := deployment.Spec.DeepCopy()
 		r.newDeployment(syrax, deploymentName, deployment)
-		err = r.Update(context.TODO(), deployment)
+		logDrift(logger, "Deployment", current, &deployment.Spec)
+		err = r.applyChildUpdate(ctx, syrax, deployment)
 	}
 	if err != nil {
-		r.Recorder.Event(syrax, "Normal", "", fmt.Sprintf("the deployment for syrax kind with name %s failed in updatation, requeue", syrax.Name))
-		return ctrl.Result{}, err
-	} else {
-		r.Recorder.Event(syrax, "Normal", "", fmt.Sprintf("the deployment for syrax kind with name %s is successfully updated", syrax.Name))
+		logger.Error(err, "Unable to reconcile deployment")
+		r.warningEvent(syrax, ReasonUpdateFailed, fmt.Sprintf("unable to update deployment %s: %v", deploymentName, err))
+		return r.handleError(ctx, syrax, err)
 	}
 
 	if ifSvcUpdated(syrax, service) {
-		log.Log.Info("Update service resource")
+		current := service.Spec.DeepCopy()
 		r.newService(syrax, serviceName, service)
-		err = r.Update(context.TODO(), service)
+		logDrift(logger, "Service", current, &service.Spec)
+		err = r.applyChildUpdate(ctx, syrax, service)
+	}
+	if err != nil {
+		logger.Error(err, "Unable to reconcile service")
+		r.warningEvent(syrax, ReasonUpdateFailed, fmt.Sprintf("unable to update service %s: %v", serviceName, err))
+		return r.handleError(ctx, syrax, err)
+	}
+	if syrax.DeletionTimestamp == nil {
+		err = r.deleteStaleConfigMaps(ctx, syrax)
 	}
 	if err != nil {
-		r.Recorder.Event(syrax, "Normal", "", fmt.Sprintf("the service for syrax kind with name %s failed in updatation, requeue", syrax.Name))
-		return ctrl.Result{}, err
-	} else {
-		r.Recorder.Event(syrax, "Normal", "", fmt.Sprintf("the service for syrax kind with name %s is successfully updated", syrax.Name))
+		logger.Error(err, "Unable to delete previous config files")
+		r.warningEvent(syrax, ReasonUpdateFailed, fmt.Sprintf("unable to delete previous config files: %v", err))
+		return r.handleError(ctx, syrax, err)
+	}
+	// The components are reconciled independently, their errors are reported
+	// in their status before being retried.
+	componentsErr := r.reconcileComponents(ctx, syrax)
+	if componentsErr != nil {
+		logger.Error(componentsErr, "Unable to reconcile components")
 	}
-	if syrax.DeletionTimestamp != nil {
-		ctrlutil.RemoveFinalizer(syrax, utils.DefaultFinalizer)
+	if syrax.DeletionTimestamp != nil && componentsErr == nil {
+		observeFinalizerCleanup(syrax)
+		logger.Info("Removing finalizer", "finalizer", utils.DefaultFinalizer)
 		time.Sleep(20 * time.Millisecond)
-		r.Update(context.TODO(), syrax)
+		if err = r.updateSyraxMetadata(ctx, syrax, func(syrax *syraxv2.Syrax) {
+			ctrlutil.RemoveFinalizer(syrax, utils.DefaultFinalizer)
+		}); err != nil {
+			logger.Error(err, "Unable to remove finalizer")
+			return r.handleError(ctx, syrax, err)
+		}
+		r.normalEvent(syrax, ReasonCleanupComplete, "the children of the syrax were cleaned up")
 	}
 
-	err = r.updateSyraxStatus(syrax, deployment, service)
+	setNotDegraded(syrax)
+	err = r.updateSyraxStatus(ctx, syrax, r.servingDeployment(ctx, syrax, deployment), service)
 	if err != nil {
-		r.Recorder.Event(syrax, "Normal", "", fmt.Sprintf("the status subresource for syrax kind with name %s failed in update", syrax.Name))
-		return ctrl.Result{}, err
+		logger.Error(err, "Unable to update syrax status")
+		return r.handleError(ctx, nil, err)
+	}
+	if componentsErr != nil {
+		return r.handleError(ctx, syrax, componentsErr)
 	}
 
-	r.Recorder.Event(syrax, "Normal", "", fmt.Sprintf("for syrax kind with name %s everything is fine", syrax.Name))
+	result = requeueIfNotReady(result, syrax, r.NotReadyResyncPeriod)
+	result = requeueForImages(result, imagesRequeueAfter)
+	logger.V(1).Info("Reconcile finished", "requeueAfter", result.RequeueAfter)
 
-	return ctrl.Result{}, nil
+	return result, nil
 }
-func (r *SyraxReconciler) updateSyraxStatus(syrax *syraxv1.Syrax, deployment *appsv1.Deployment, service *corev1.Service) error {
+func (r *SyraxReconciler) updateSyraxStatus(ctx context.Context, syrax *syraxv2.Syrax, deployment *appsv1.Deployment, service *corev1.Service) error {
 
 	syrax.Status.AvailableReplicas = &deployment.Status.AvailableReplicas
+	syrax.Status.ServiceName = service.Name
+	syrax.Status.ServiceType = service.Spec.Type
+	setRolloutStatus(syrax, deployment)
+	setRestartStatus(syrax, deployment)
+	setReadyCondition(syrax, deployment, service)
 
-	err := r.Status().Update(context.TODO(), syrax)
+	ctx, span := r.startSpan(ctx, "UpdateStatus")
+	err := r.Status().Update(ctx, syrax)
+	endSpan(span, err)
+	if err == nil {
+		recordSyraxMetrics(syrax)
+	}
 	return err
 
 }
 
+// updateSyraxMetadata writes the changes mutate makes to the metadata of the
+// syrax. The spec in memory, resolved from the template of the syrax, and the
+// status are left out of the request and kept as they are.
+func (r *SyraxReconciler) updateSyraxMetadata(ctx context.Context, syrax *syraxv2.Syrax, mutate func(*syraxv2.Syrax)) error {
+	updated := syrax.DeepCopy()
+	mutate(updated)
+	if err := r.Patch(ctx, updated, client.MergeFromWithOptions(syrax, client.MergeFromWithOptimisticLock{})); err != nil {
+		return err
+	}
+	syrax.ObjectMeta = updated.ObjectMeta
+	return nil
+}
+
+// patchSyraxStatus writes the changes mutate makes to the status of the syrax
+// right away, for the steps that must not be repeated when the status update
+// at the end of the reconcile fails. The spec in memory is kept as it is.
+func (r *SyraxReconciler) patchSyraxStatus(ctx context.Context, syrax *syraxv2.Syrax, mutate func(*syraxv2.Syrax)) error {
+	patched := syrax.DeepCopy()
+	mutate(patched)
+	if err := r.Status().Patch(ctx, patched, client.MergeFrom(syrax)); err != nil {
+		return err
+	}
+	mutate(syrax)
+	syrax.ResourceVersion = patched.ResourceVersion
+	return nil
+}
+
 var (
 	jobOwnerKey = ".metadata.controller"
 )
@@ -198,10 +389,34 @@ func (r *SyraxReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	}); err != nil {
 		return err
 	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &targaryenv2.Syrax{}, templateRefKey, func(rawObj client.Object) []string {
+		syrax := rawObj.(*targaryenv2.Syrax)
+		if syrax.Spec.TemplateRef == nil {
+			return nil
+		}
+		return []string{syrax.Spec.TemplateRef.Name}
+	}); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &targaryenv2.Syrax{}, registryCredentialsKey, func(rawObj client.Object) []string {
+		syrax := rawObj.(*targaryenv2.Syrax)
+		if syrax.Spec.RegistryCredentialsRef == nil {
+			return nil
+		}
+		return []string{syrax.Spec.RegistryCredentialsRef.Namespace + "/" + syrax.Spec.RegistryCredentialsRef.Name}
+	}); err != nil {
+		return err
+	}
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&targaryenv1.Syrax{}).
+		WithOptions(controller.Options{RateLimiter: r.RateLimiter}).
+		For(&targaryenv2.Syrax{}).
 		Owns(&appsv1.Deployment{}, builder.MatchEveryOwner).
 		Owns(&corev1.Service{}, builder.MatchEveryOwner).
+		Owns(&corev1.Secret{}, builder.MatchEveryOwner).
+		Owns(&corev1.ConfigMap{}, builder.MatchEveryOwner).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.syraxesForRegistryCredentials)).
+		Watches(&targaryenv2.SyraxTemplate{}, handler.EnqueueRequestsFromMapFunc(r.syraxesForTemplate)).
+		Watches(&targaryenv2.SyraxPolicy{}, handler.EnqueueRequestsFromMapFunc(r.syraxesForPolicy)).
 		Complete(r)
 }
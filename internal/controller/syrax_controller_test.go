@@ -21,13 +21,22 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	targaryenv1 "resource.controller.sigs/resource-controller-k8s-sigs/api/v1"
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
 )
 
 var _ = Describe("Syrax Controller", func() {
@@ -40,18 +49,25 @@ var _ = Describe("Syrax Controller", func() {
 			Name:      resourceName,
 			Namespace: "default", // TODO(user):Modify as needed
 		}
-		syrax := &targaryenv1.Syrax{}
+		syrax := &targaryenv2.Syrax{}
 
 		BeforeEach(func() {
 			By("creating the custom resource for the Kind Syrax")
 			err := k8sClient.Get(ctx, typeNamespacedName, syrax)
 			if err != nil && errors.IsNotFound(err) {
-				resource := &targaryenv1.Syrax{
+				resource := &targaryenv2.Syrax{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      resourceName,
 						Namespace: "default",
 					},
-					// TODO(user): Specify other spec details if needed.
+					Spec: targaryenv2.SyraxSpec{
+						DeploymentSpec: targaryenv2.DeploymentSpec{
+							Image: "nginx:1.25",
+						},
+						ServiceSpec: targaryenv2.ServiceSpec{
+							Ports: []targaryenv2.ServicePort{{Port: 80}},
+						},
+					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 			}
@@ -59,7 +75,7 @@ var _ = Describe("Syrax Controller", func() {
 
 		AfterEach(func() {
 			// TODO(user): Cleanup logic after each test, like removing the resource instance.
-			resource := &targaryenv1.Syrax{}
+			resource := &targaryenv2.Syrax{}
 			err := k8sClient.Get(ctx, typeNamespacedName, resource)
 			Expect(err).NotTo(HaveOccurred())
 
@@ -69,8 +85,9 @@ var _ = Describe("Syrax Controller", func() {
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
 			controllerReconciler := &SyraxReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 			}
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -81,4 +98,158 @@ var _ = Describe("Syrax Controller", func() {
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
 	})
+
+	Context("When a rollout strategy is configured", func() {
+		const resourceName = "rollout-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			maxSurge := intstr.FromInt32(1)
+			maxUnavailable := intstr.FromString("10%")
+			resource := newSyrax(typeNamespacedName)
+			resource.Spec.DeploymentSpec.Replicas = ptr.To[int32](2)
+			resource.Spec.DeploymentSpec.Strategy = &appsv1.DeploymentStrategy{
+				Type: appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{
+					MaxSurge:       &maxSurge,
+					MaxUnavailable: &maxUnavailable,
+				},
+			}
+			resource.Spec.DeploymentSpec.MinReadySeconds = 5
+			resource.Spec.DeploymentSpec.ProgressDeadlineSeconds = ptr.To[int32](120)
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteSyrax(ctx, typeNamespacedName)
+		})
+
+		It("should propagate the strategy and report the rollout status", func() {
+			reconcileSyrax(ctx, newReconciler(), typeNamespacedName)
+
+			deployment := ownedDeployment(ctx, typeNamespacedName)
+			Expect(deployment.Spec.Strategy.Type).To(Equal(appsv1.RollingUpdateDeploymentStrategyType))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxSurge.IntValue()).To(Equal(1))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxUnavailable.String()).To(Equal("10%"))
+			Expect(deployment.Spec.MinReadySeconds).To(Equal(int32(5)))
+			Expect(*deployment.Spec.ProgressDeadlineSeconds).To(Equal(int32(120)))
+
+			syrax := &targaryenv2.Syrax{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+			Expect(syrax.Status.TargetImage).To(Equal("nginx:1.25"))
+			Expect(syrax.Status.ServiceName).To(HavePrefix(resourceName + "-"))
+			Expect(syrax.Status.ServiceType).To(Equal(corev1.ServiceTypeNodePort))
+			Expect(meta.FindStatusCondition(syrax.Status.Conditions, targaryenv2.ConditionProgressing)).NotTo(BeNil())
+		})
+	})
 })
+
+// newSyrax returns a syrax running nginx:1.25 behind a service on port 80,
+// for the tests to adjust before creating it.
+func newSyrax(name types.NamespacedName) *targaryenv2.Syrax {
+	return &targaryenv2.Syrax{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name.Name,
+			Namespace: name.Namespace,
+		},
+		Spec: targaryenv2.SyraxSpec{
+			DeploymentSpec: targaryenv2.DeploymentSpec{
+				Image: "nginx:1.25",
+			},
+			ServiceSpec: targaryenv2.ServiceSpec{
+				Ports: []targaryenv2.ServicePort{{Port: 80}},
+			},
+		},
+	}
+}
+
+// newReconciler returns a reconciler talking to envtest with a fake event
+// recorder.
+func newReconciler() *SyraxReconciler {
+	return &SyraxReconciler{
+		Client:   k8sClient,
+		Scheme:   k8sClient.Scheme(),
+		Recorder: record.NewFakeRecorder(100),
+	}
+}
+
+// reconcileSyrax reconciles the named syrax once and expects it to succeed.
+func reconcileSyrax(ctx context.Context, r *SyraxReconciler, name types.NamespacedName) reconcile.Result {
+	result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: name})
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+	return result
+}
+
+// deleteSyrax deletes the named syrax unless a test already did. Its
+// finalizer is removed first, as no controller runs to clean up after it.
+func deleteSyrax(ctx context.Context, name types.NamespacedName) {
+	resource := &targaryenv2.Syrax{}
+	err := k8sClient.Get(ctx, name, resource)
+	if errors.IsNotFound(err) {
+		return
+	}
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+	if len(resource.Finalizers) > 0 {
+		resource.Finalizers = nil
+		ExpectWithOffset(1, k8sClient.Update(ctx, resource)).To(Succeed())
+	}
+	ExpectWithOffset(1, client.IgnoreNotFound(k8sClient.Delete(ctx, resource))).To(Succeed())
+}
+
+// ownedDeployment returns the deployment controlled by the named syrax.
+func ownedDeployment(ctx context.Context, syraxName types.NamespacedName) *appsv1.Deployment {
+	deployments := &appsv1.DeploymentList{}
+	ExpectWithOffset(1, k8sClient.List(ctx, deployments, client.InNamespace(syraxName.Namespace))).To(Succeed())
+	var deployment *appsv1.Deployment
+	for i := range deployments.Items {
+		owner := metav1.GetControllerOf(&deployments.Items[i])
+		if owner != nil && owner.Name == syraxName.Name && deployments.Items[i].Labels[utils.TrackLabel] == "" &&
+			deployments.Items[i].Labels[utils.ComponentLabel] == "" {
+			deployment = &deployments.Items[i]
+		}
+	}
+	ExpectWithOffset(1, deployment).NotTo(BeNil())
+	return deployment
+}
+
+// deleteOwnedChildren deletes the children controlled by the syrax, which
+// envtest does not garbage collect.
+func deleteOwnedChildren(ctx context.Context, syraxName types.NamespacedName) {
+	lists := []client.ObjectList{
+		&appsv1.DeploymentList{}, &corev1.ServiceList{}, &corev1.ConfigMapList{}, &corev1.SecretList{},
+	}
+	for _, list := range lists {
+		ExpectWithOffset(1, k8sClient.List(ctx, list, client.InNamespace(syraxName.Namespace))).To(Succeed())
+		ExpectWithOffset(1, meta.EachListItem(list, func(object runtime.Object) error {
+			child := object.(client.Object)
+			owner := metav1.GetControllerOf(child)
+			if owner == nil || owner.Name != syraxName.Name {
+				return nil
+			}
+			return k8sClient.Delete(ctx, child)
+		})).To(Succeed())
+	}
+}
+
+// markRolledOut fakes the deployment controller by reporting every replica of
+// the deployment as updated and available.
+func markRolledOut(ctx context.Context, deployment *appsv1.Deployment) {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	deployment.Status = appsv1.DeploymentStatus{
+		ObservedGeneration: deployment.Generation,
+		Replicas:           replicas,
+		UpdatedReplicas:    replicas,
+		ReadyReplicas:      replicas,
+		AvailableReplicas:  replicas,
+	}
+	ExpectWithOffset(1, k8sClient.Status().Update(ctx, deployment)).To(Succeed())
+}
@@ -0,0 +1,357 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// SyraxSetReconciler reconciles a SyraxSet object
+type SyraxSetReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=targaryen.resource.controller.sigs,resources=syraxsets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=targaryen.resource.controller.sigs,resources=syraxsets/status,verbs=get;update
+//+kubebuilder:rbac:groups=targaryen.resource.controller.sigs,resources=syraxsets/finalizers,verbs=update
+//+kubebuilder:rbac:groups=targaryen.resource.controller.sigs,resources=syraxes,verbs=create;delete
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+// placeholder matches the {{param}} placeholders of the template of a set.
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// Reconcile creates, updates and deletes the Syraxes of a SyraxSet so that
+// there is one per set of parameters of its generators.
+func (r *SyraxSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	set := &syraxv2.SyraxSet{}
+	if err := r.Get(ctx, req.NamespacedName, set); err != nil {
+		if apierrors.IsNotFound(err) {
+			setEvents.forget(req.NamespacedName)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if set.DeletionTimestamp != nil {
+		// the garbage collector deletes the Syraxes the set controls.
+		return ctrl.Result{}, nil
+	}
+
+	params, err := r.generateParams(ctx, set)
+	if err != nil {
+		// The Syraxes are kept as they are, a generator that cannot be read
+		// must not delete them.
+		logger.Error(err, "Unable to generate the parameters of the set")
+		r.warningEvent(set, ReasonGenerateFailed, err.Error())
+		if statusErr := r.updateSetStatus(ctx, set, set.Status.Syraxes, set.Status.ReadySyraxes, ReasonGenerateFailed, err); statusErr != nil {
+			return ctrl.Result{}, statusErr
+		}
+		return ctrl.Result{}, err
+	}
+
+	var errs []error
+	desired := map[types.NamespacedName]bool{}
+	ready := int32(0)
+	for _, element := range params {
+		syrax, err := renderSyrax(set, element)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		key := client.ObjectKeyFromObject(syrax)
+		if desired[key] {
+			errs = append(errs, fmt.Errorf("syrax %s is generated more than once, its name needs more placeholders", key))
+			continue
+		}
+		desired[key] = true
+		current, err := r.applySyrax(ctx, set, syrax)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("syrax %s: %w", key, err))
+			continue
+		}
+		if meta.IsStatusConditionTrue(current.Status.Conditions, syraxv2.ConditionReady) {
+			ready++
+		}
+	}
+	// Deleting the Syraxes of the elements that failed to render would take
+	// them down for a typo in the template.
+	if len(errs) == 0 {
+		if err := r.deleteStaleSyraxes(ctx, set, desired); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	err = stderrors.Join(errs...)
+	if err != nil {
+		logger.Error(err, "Unable to reconcile the syraxes of the set")
+	}
+	if statusErr := r.updateSetStatus(ctx, set, int32(len(desired)), ready, "ApplyFailed", err); statusErr != nil {
+		return ctrl.Result{}, statusErr
+	}
+	return ctrl.Result{}, err
+}
+
+// generateParams returns the sets of parameters of every generator of the set.
+func (r *SyraxSetReconciler) generateParams(ctx context.Context, set *syraxv2.SyraxSet) ([]map[string]string, error) {
+	var params []map[string]string
+	for _, generator := range set.Spec.Generators {
+		switch {
+		case generator.List != nil:
+			params = append(params, generator.List.Elements...)
+		case generator.Namespaces != nil:
+			selector, err := metav1.LabelSelectorAsSelector(&generator.Namespaces.Selector)
+			if err != nil {
+				return nil, err
+			}
+			namespaces := &corev1.NamespaceList{}
+			if err := r.List(ctx, namespaces, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+				return nil, err
+			}
+			for _, namespace := range namespaces.Items {
+				if namespace.DeletionTimestamp == nil {
+					params = append(params, map[string]string{"namespace": namespace.Name})
+				}
+			}
+		case generator.ConfigMap != nil:
+			configMap := &corev1.ConfigMap{}
+			if err := r.Get(ctx, types.NamespacedName{Namespace: generator.ConfigMap.Namespace, Name: generator.ConfigMap.Name}, configMap); err != nil {
+				if apierrors.IsNotFound(err) {
+					return nil, &missingReferenceError{err: fmt.Errorf("configmap %s/%s not found", generator.ConfigMap.Namespace, generator.ConfigMap.Name)}
+				}
+				return nil, err
+			}
+			keys := make([]string, 0, len(configMap.Data))
+			for key := range configMap.Data {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				params = append(params, map[string]string{"key": key, "value": configMap.Data[key]})
+			}
+		}
+	}
+	return params, nil
+}
+
+// renderSyrax returns the Syrax of the set for a set of parameters, with its
+// placeholders replaced and the labels pointing back to the set.
+func renderSyrax(set *syraxv2.SyraxSet, params map[string]string) (*syraxv2.Syrax, error) {
+	raw, err := json.Marshal(set.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	rendered := placeholder.ReplaceAllStringFunc(string(raw), func(match string) string {
+		value, ok := params[placeholder.FindStringSubmatch(match)[1]]
+		if !ok {
+			missing = append(missing, match)
+			return match
+		}
+		// the placeholder sits inside a JSON string.
+		quoted, _ := json.Marshal(value)
+		return string(quoted[1 : len(quoted)-1])
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("no parameter for %s in %v", strings.Join(missing, ", "), params)
+	}
+	template := syraxv2.SyraxSetTemplate{}
+	if err := json.Unmarshal([]byte(rendered), &template); err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{}
+	for k, v := range template.Metadata.Labels {
+		labels[k] = v
+	}
+	labels[utils.SyraxSetLabel] = set.Name
+	syrax := &syraxv2.Syrax{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        template.Metadata.Name,
+			Namespace:   template.Metadata.Namespace,
+			Labels:      labels,
+			Annotations: template.Metadata.Annotations,
+		},
+		Spec: template.Spec,
+	}
+	if syrax.Namespace == "" {
+		return nil, fmt.Errorf("the namespace of syrax %s is empty with %v", syrax.Name, params)
+	}
+	return syrax, nil
+}
+
+// applySyrax creates the Syrax or updates it when it differs from the
+// template, and returns the Syrax as stored. The set controls the Syraxes it
+// creates, so that the garbage collector deletes them with the set. A Syrax of
+// the same name that the set does not control is left alone.
+func (r *SyraxSetReconciler) applySyrax(ctx context.Context, set *syraxv2.SyraxSet, syrax *syraxv2.Syrax) (*syraxv2.Syrax, error) {
+	current := &syraxv2.Syrax{}
+	err := r.Get(ctx, client.ObjectKeyFromObject(syrax), current)
+	if apierrors.IsNotFound(err) {
+		if err := ctrl.SetControllerReference(set, syrax, r.Scheme); err != nil {
+			return nil, err
+		}
+		if err := r.Create(ctx, syrax); err != nil {
+			r.warningEvent(set, ReasonCreateFailed, fmt.Sprintf("unable to create syrax %s/%s: %v", syrax.Namespace, syrax.Name, err))
+			return nil, err
+		}
+		r.normalEvent(set, ReasonCreated, fmt.Sprintf("created syrax %s/%s", syrax.Namespace, syrax.Name))
+		return syrax, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !metav1.IsControlledBy(current, set) {
+		message := fmt.Sprintf("syrax %s/%s already exists and does not belong to the set", syrax.Namespace, syrax.Name)
+		r.warningEvent(set, ReasonNameConflict, message)
+		return nil, stderrors.New(message)
+	}
+
+	changed := !equality.Semantic.DeepEqual(current.Spec, syrax.Spec)
+	for k, v := range syrax.Labels {
+		changed = changed || current.Labels[k] != v
+	}
+	for k, v := range syrax.Annotations {
+		changed = changed || current.Annotations[k] != v
+	}
+	if !changed {
+		return current, nil
+	}
+	current.Spec = syrax.Spec
+	for k, v := range syrax.Labels {
+		current.Labels[k] = v
+	}
+	if len(syrax.Annotations) > 0 && current.Annotations == nil {
+		current.Annotations = map[string]string{}
+	}
+	for k, v := range syrax.Annotations {
+		current.Annotations[k] = v
+	}
+	if err := r.Update(ctx, current); err != nil {
+		r.warningEvent(set, ReasonUpdateFailed, fmt.Sprintf("unable to update syrax %s/%s: %v", syrax.Namespace, syrax.Name, err))
+		return nil, err
+	}
+	r.normalEvent(set, ReasonUpdated, fmt.Sprintf("updated syrax %s/%s to the template", syrax.Namespace, syrax.Name))
+	return current, nil
+}
+
+// listSyraxes returns the Syraxes generated by the set in every namespace.
+func (r *SyraxSetReconciler) listSyraxes(ctx context.Context, set *syraxv2.SyraxSet) ([]syraxv2.Syrax, error) {
+	syraxes := &syraxv2.SyraxList{}
+	err := r.List(ctx, syraxes, client.MatchingLabels{utils.SyraxSetLabel: set.Name})
+	return syraxes.Items, err
+}
+
+// deleteStaleSyraxes deletes the Syraxes of the set that no generator
+// produces anymore.
+func (r *SyraxSetReconciler) deleteStaleSyraxes(ctx context.Context, set *syraxv2.SyraxSet, desired map[types.NamespacedName]bool) error {
+	syraxes, err := r.listSyraxes(ctx, set)
+	if err != nil {
+		return err
+	}
+	for i := range syraxes {
+		syrax := &syraxes[i]
+		if desired[client.ObjectKeyFromObject(syrax)] || !metav1.IsControlledBy(syrax, set) {
+			continue
+		}
+		if err := client.IgnoreNotFound(r.Delete(ctx, syrax)); err != nil {
+			return err
+		}
+		r.normalEvent(set, ReasonDeleted, fmt.Sprintf("deleted syrax %s/%s", syrax.Namespace, syrax.Name))
+	}
+	return nil
+}
+
+// updateSetStatus reports how many of the Syraxes of the set are ready. The
+// set is Ready once every Syrax is, err is reported with reason otherwise.
+func (r *SyraxSetReconciler) updateSetStatus(ctx context.Context, set *syraxv2.SyraxSet, total, ready int32, reason string, err error) error {
+	condition := metav1.Condition{
+		Type:               syraxv2.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Ready",
+		Message:            fmt.Sprintf("all %d syraxes are ready", total),
+		ObservedGeneration: set.Generation,
+	}
+	switch {
+	case err != nil:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reason
+		condition.Message = err.Error()
+	case ready < total:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "SyraxesNotReady"
+		condition.Message = fmt.Sprintf("%d of %d syraxes are ready", ready, total)
+	}
+	set.Status.Syraxes = total
+	set.Status.ReadySyraxes = ready
+	set.Status.ObservedGeneration = set.Generation
+	meta.SetStatusCondition(&set.Status.Conditions, condition)
+	return r.Status().Update(ctx, set)
+}
+
+// setsForNamespace returns the requests of the sets with a namespaces
+// generator, whose parameters may change with the namespace.
+func (r *SyraxSetReconciler) setsForNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.setsMatching(ctx, func(generator syraxv2.SyraxSetGenerator) bool {
+		return generator.Namespaces != nil
+	})
+}
+
+// setsForConfigMap returns the requests of the sets generated from the
+// ConfigMap.
+func (r *SyraxSetReconciler) setsForConfigMap(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.setsMatching(ctx, func(generator syraxv2.SyraxSetGenerator) bool {
+		return generator.ConfigMap != nil && generator.ConfigMap.Namespace == obj.GetNamespace() &&
+			generator.ConfigMap.Name == obj.GetName()
+	})
+}
+
+func (r *SyraxSetReconciler) setsMatching(ctx context.Context, match func(syraxv2.SyraxSetGenerator) bool) []reconcile.Request {
+	sets := &syraxv2.SyraxSetList{}
+	if err := r.List(ctx, sets); err != nil {
+		log.FromContext(ctx).Error(err, "Unable to list the syraxsets")
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range sets.Items {
+		for _, generator := range sets.Items[i].Spec.Generators {
+			if match(generator) {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&sets.Items[i])})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SyraxSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&syraxv2.SyraxSet{}).
+		Owns(&syraxv2.Syrax{}).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.setsForNamespace)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.setsForConfigMap)).
+		Complete(r)
+}
@@ -0,0 +1,245 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"resource.controller.sigs/resource-controller-k8s-sigs/utils"
+)
+
+var _ = Describe("SyraxSet Controller", func() {
+	const setName = "tenants"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{Name: setName}
+	named := func(name string) types.NamespacedName {
+		return types.NamespacedName{Name: name, Namespace: "default"}
+	}
+
+	var controllerReconciler *SyraxSetReconciler
+
+	newSet := func(generators ...targaryenv2.SyraxSetGenerator) *targaryenv2.SyraxSet {
+		return &targaryenv2.SyraxSet{
+			ObjectMeta: metav1.ObjectMeta{Name: setName},
+			Spec: targaryenv2.SyraxSetSpec{
+				Generators: generators,
+				Template: targaryenv2.SyraxSetTemplate{
+					Metadata: targaryenv2.SyraxSetTemplateMeta{
+						Name:      "shop-{{tenant}}",
+						Namespace: "default",
+						Labels:    map[string]string{"tenant": "{{tenant}}"},
+					},
+					Spec: targaryenv2.SyraxSpec{
+						Labels: map[string]string{"tenant": "{{tenant}}"},
+						DeploymentSpec: targaryenv2.DeploymentSpec{
+							Image: "shop/api:{{version}}",
+						},
+						ServiceSpec: targaryenv2.ServiceSpec{
+							Ports: []targaryenv2.ServicePort{{Port: 80}},
+						},
+					},
+				},
+			},
+		}
+	}
+	listOf := func(elements ...map[string]string) targaryenv2.SyraxSetGenerator {
+		return targaryenv2.SyraxSetGenerator{List: &targaryenv2.ListGenerator{Elements: elements}}
+	}
+	reconcileSet := func() error {
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		return err
+	}
+
+	var recorder *record.FakeRecorder
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(100)
+		controllerReconciler = &SyraxSetReconciler{
+			Client:   k8sClient,
+			Scheme:   k8sClient.Scheme(),
+			Recorder: recorder,
+		}
+	})
+
+	AfterEach(func() {
+		set := &targaryenv2.SyraxSet{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, set)).To(Succeed())
+
+		By("owning the syraxes of the set")
+		syraxes := &targaryenv2.SyraxList{}
+		Expect(k8sClient.List(ctx, syraxes, client.MatchingLabels{utils.SyraxSetLabel: setName})).To(Succeed())
+		for i := range syraxes.Items {
+			Expect(metav1.IsControlledBy(&syraxes.Items[i], set)).To(BeTrue())
+			// envtest runs no garbage collector to delete them with the set.
+			Expect(k8sClient.Delete(ctx, &syraxes.Items[i])).To(Succeed())
+		}
+		Expect(k8sClient.Delete(ctx, set)).To(Succeed())
+		setEvents.forget(typeNamespacedName)
+	})
+
+	It("should stamp out a syrax per element of a list", func() {
+		set := newSet(listOf(
+			map[string]string{"tenant": "acme", "version": "1.0"},
+			map[string]string{"tenant": "globex", "version": "2.0"},
+		))
+		Expect(k8sClient.Create(ctx, set)).To(Succeed())
+		Expect(reconcileSet()).To(Succeed())
+
+		acme := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, named("shop-acme"), acme)).To(Succeed())
+		Expect(acme.Spec.DeploymentSpec.Image).To(Equal("shop/api:1.0"))
+		Expect(acme.Spec.Labels).To(HaveKeyWithValue("tenant", "acme"))
+		Expect(acme.Labels).To(HaveKeyWithValue("tenant", "acme"))
+		Expect(acme.Labels).To(HaveKeyWithValue(utils.SyraxSetLabel, setName))
+		globex := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, named("shop-globex"), globex)).To(Succeed())
+		Expect(globex.Spec.DeploymentSpec.Image).To(Equal("shop/api:2.0"))
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, set)).To(Succeed())
+		Expect(set.Status.Syraxes).To(Equal(int32(2)))
+		Expect(set.Status.ReadySyraxes).To(BeZero())
+		Expect(meta.IsStatusConditionFalse(set.Status.Conditions, targaryenv2.ConditionReady)).To(BeTrue())
+
+		By("reporting the set Ready once every syrax is")
+		for _, syrax := range []*targaryenv2.Syrax{acme, globex} {
+			syrax.Status.AvailableReplicas = new(int32)
+			meta.SetStatusCondition(&syrax.Status.Conditions, metav1.Condition{
+				Type:   targaryenv2.ConditionReady,
+				Status: metav1.ConditionTrue,
+				Reason: "Available",
+			})
+			Expect(k8sClient.Status().Update(ctx, syrax)).To(Succeed())
+		}
+		Expect(reconcileSet()).To(Succeed())
+		Expect(k8sClient.Get(ctx, typeNamespacedName, set)).To(Succeed())
+		Expect(set.Status.ReadySyraxes).To(Equal(int32(2)))
+		Expect(meta.IsStatusConditionTrue(set.Status.Conditions, targaryenv2.ConditionReady)).To(BeTrue())
+
+		By("updating and deleting the syraxes with the list")
+		set.Spec.Generators = []targaryenv2.SyraxSetGenerator{listOf(map[string]string{"tenant": "acme", "version": "1.1"})}
+		Expect(k8sClient.Update(ctx, set)).To(Succeed())
+		Expect(reconcileSet()).To(Succeed())
+		Expect(k8sClient.Get(ctx, named("shop-acme"), acme)).To(Succeed())
+		Expect(acme.Spec.DeploymentSpec.Image).To(Equal("shop/api:1.1"))
+		err := k8sClient.Get(ctx, named("shop-globex"), &targaryenv2.Syrax{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should generate a syrax per entry of a configmap", func() {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "tenant-versions", Namespace: "default"},
+			Data:       map[string]string{"acme": "1.0", "initech": "3.0"},
+		}
+		Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, configMap)).To(Succeed())
+		})
+
+		set := newSet(targaryenv2.SyraxSetGenerator{ConfigMap: &targaryenv2.ConfigMapGenerator{Namespace: configMap.Namespace, Name: configMap.Name}})
+		set.Spec.Template.Metadata.Name = "shop-{{key}}"
+		set.Spec.Template.Metadata.Labels = nil
+		set.Spec.Template.Spec.Labels = nil
+		set.Spec.Template.Spec.DeploymentSpec.Image = "shop/api:{{value}}"
+		Expect(k8sClient.Create(ctx, set)).To(Succeed())
+		Expect(reconcileSet()).To(Succeed())
+
+		initech := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, named("shop-initech"), initech)).To(Succeed())
+		Expect(initech.Spec.DeploymentSpec.Image).To(Equal("shop/api:3.0"))
+		Expect(k8sClient.Get(ctx, named("shop-acme"), &targaryenv2.Syrax{})).To(Succeed())
+	})
+
+	It("should generate a syrax per selected namespace", func() {
+		for _, name := range []string{"tenant-a", "tenant-b", "not-a-tenant"} {
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+			if name != "not-a-tenant" {
+				namespace.Labels = map[string]string{"tenant": "true"}
+			}
+			Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+		}
+
+		set := newSet(targaryenv2.SyraxSetGenerator{Namespaces: &targaryenv2.NamespaceGenerator{
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}},
+		}})
+		set.Spec.Template.Metadata.Name = "shop"
+		set.Spec.Template.Metadata.Namespace = "{{namespace}}"
+		set.Spec.Template.Metadata.Labels = nil
+		set.Spec.Template.Spec.Labels = nil
+		set.Spec.Template.Spec.DeploymentSpec.Image = "shop/api:1.0"
+		Expect(k8sClient.Create(ctx, set)).To(Succeed())
+		Expect(reconcileSet()).To(Succeed())
+
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: "tenant-a", Name: "shop"}, &targaryenv2.Syrax{})).To(Succeed())
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: "tenant-b", Name: "shop"}, &targaryenv2.Syrax{})).To(Succeed())
+		err := k8sClient.Get(ctx, types.NamespacedName{Namespace: "not-a-tenant", Name: "shop"}, &targaryenv2.Syrax{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		By("deleting the syrax of a namespace being deleted")
+		// envtest runs no namespace controller, the namespace stays terminating.
+		Expect(k8sClient.Delete(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-b"}})).To(Succeed())
+		Expect(reconcileSet()).To(Succeed())
+		err = k8sClient.Get(ctx, types.NamespacedName{Namespace: "tenant-b", Name: "shop"}, &targaryenv2.Syrax{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(k8sClient.Get(ctx, typeNamespacedName, set)).To(Succeed())
+		Expect(set.Status.Syraxes).To(Equal(int32(1)))
+	})
+
+	It("should leave alone a syrax of the same name it does not own", func() {
+		syrax := newSyrax(named("shop-acme"))
+		syrax.Labels = map[string]string{utils.SyraxSetLabel: setName}
+		Expect(k8sClient.Create(ctx, syrax)).To(Succeed())
+
+		set := newSet(listOf(map[string]string{"tenant": "acme", "version": "1.0"}))
+		Expect(k8sClient.Create(ctx, set)).To(Succeed())
+		Expect(reconcileSet()).NotTo(Succeed())
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning " + ReasonNameConflict)))
+
+		By("not deleting it once the set stops generating it")
+		Expect(k8sClient.Get(ctx, typeNamespacedName, set)).To(Succeed())
+		set.Spec.Generators = []targaryenv2.SyraxSetGenerator{listOf(map[string]string{"tenant": "initech", "version": "1.0"})}
+		Expect(k8sClient.Update(ctx, set)).To(Succeed())
+		Expect(reconcileSet()).To(Succeed())
+		Expect(k8sClient.Get(ctx, named("shop-acme"), syrax)).To(Succeed())
+		Expect(syrax.Spec.DeploymentSpec.Image).To(Equal("nginx:1.25"))
+		Expect(k8sClient.Delete(ctx, syrax)).To(Succeed())
+	})
+
+	It("should keep the syraxes when an element cannot be rendered", func() {
+		set := newSet(listOf(map[string]string{"tenant": "acme", "version": "1.0"}))
+		Expect(k8sClient.Create(ctx, set)).To(Succeed())
+		Expect(reconcileSet()).To(Succeed())
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, set)).To(Succeed())
+		set.Spec.Generators = []targaryenv2.SyraxSetGenerator{listOf(map[string]string{"tenant": "acme"})}
+		Expect(k8sClient.Update(ctx, set)).To(Succeed())
+		Expect(reconcileSet()).NotTo(Succeed())
+
+		Expect(k8sClient.Get(ctx, named("shop-acme"), &targaryenv2.Syrax{})).To(Succeed())
+		Expect(k8sClient.Get(ctx, typeNamespacedName, set)).To(Succeed())
+		condition := meta.FindStatusCondition(set.Status.Conditions, targaryenv2.ConditionReady)
+		Expect(condition.Reason).To(Equal("ApplyFailed"))
+		Expect(condition.Message).To(ContainSubstring("{{version}}"))
+	})
+
+	It("should not repeat the events of a failing generator", func() {
+		set := newSet(targaryenv2.SyraxSetGenerator{ConfigMap: &targaryenv2.ConfigMapGenerator{Namespace: "default", Name: "missing"}})
+		Expect(k8sClient.Create(ctx, set)).To(Succeed())
+		Expect(reconcileSet()).NotTo(Succeed())
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning " + ReasonGenerateFailed)))
+
+		Expect(reconcileSet()).NotTo(Succeed())
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
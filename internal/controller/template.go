@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// templateRefKey indexes the syraxes by the name of their template.
+const templateRefKey = ".spec.templateRef.name"
+
+// applyTemplate merges the spec of the syrax over the defaults of its
+// template; see SyraxTemplate.Apply.
+func (r *SyraxReconciler) applyTemplate(ctx context.Context, syrax *syraxv2.Syrax) error {
+	if syrax.Spec.TemplateRef == nil {
+		syrax.Status.Template = nil
+		return nil
+	}
+	template := &syraxv2.SyraxTemplate{}
+	if err := r.Get(ctx, client.ObjectKey{Name: syrax.Spec.TemplateRef.Name}, template); err != nil {
+		if apierrors.IsNotFound(err) {
+			return &missingReferenceError{err: fmt.Errorf("syraxtemplate %s not found", syrax.Spec.TemplateRef.Name)}
+		}
+		return err
+	}
+	return template.Apply(syrax)
+}
+
+// syraxesForTemplate returns a request for every syrax referring to the
+// template, so that a change of the template rolls out to all of them.
+func (r *SyraxReconciler) syraxesForTemplate(ctx context.Context, template client.Object) []reconcile.Request {
+	syraxes := &syraxv2.SyraxList{}
+	if err := r.List(ctx, syraxes, client.MatchingFields{templateRefKey: template.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "Unable to list the syraxes of template", "template", template.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(syraxes.Items))
+	for i := range syraxes.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&syraxes.Items[i])})
+	}
+	return requests
+}
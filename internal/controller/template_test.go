@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+)
+
+var _ = Describe("Syrax templates", func() {
+	const resourceName = "template-resource"
+	const templateName = "template-defaults"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+
+	var controllerReconciler *SyraxReconciler
+
+	BeforeEach(func() {
+		template := &targaryenv2.SyraxTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: templateName},
+			Spec: targaryenv2.SyraxTemplateSpec{
+				ImageRegistry: "registry.example.com/team",
+				Labels:        map[string]string{"team": "payments", "tier": "backend"},
+				Env:           []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "info"}},
+				DeploymentSpec: &targaryenv2.TemplateDeploymentSpec{
+					Replicas: ptr.To[int32](3),
+					Resources: &corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("500m"),
+							corev1.ResourceMemory: resource.MustParse("256Mi"),
+						},
+					},
+					ReadinessProbe: &corev1.Probe{
+						ProbeHandler: corev1.ProbeHandler{
+							HTTPGet: &corev1.HTTPGetAction{Path: "/ready", Port: intstr.FromInt32(8080)},
+						},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, template)).To(Succeed())
+
+		syrax := newSyrax(typeNamespacedName)
+		syrax.Spec.TemplateRef = &targaryenv2.TemplateReference{Name: templateName}
+		syrax.Spec.Labels = map[string]string{"tier": "api"}
+		syrax.Spec.Env = []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}}
+		syrax.Spec.DeploymentSpec.Image = "shop/api:1.0"
+		syrax.Spec.DeploymentSpec.Resources = &corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+		}
+		syrax.Spec.ServiceSpec.ServiceType = corev1.ServiceTypeClusterIP
+		Expect(k8sClient.Create(ctx, syrax)).To(Succeed())
+
+		controllerReconciler = newReconciler()
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+
+		template := &targaryenv2.SyraxTemplate{ObjectMeta: metav1.ObjectMeta{Name: templateName}}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, template))).To(Succeed())
+
+		deleteOwnedChildren(ctx, typeNamespacedName)
+	})
+
+	It("should merge the template defaults under the spec", func() {
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		deployment := ownedDeployment(ctx, typeNamespacedName)
+		container := deployment.Spec.Template.Spec.Containers[0]
+		Expect(*deployment.Spec.Replicas).To(Equal(int32(3)))
+		Expect(container.Image).To(Equal("registry.example.com/team/shop/api:1.0"))
+		Expect(deployment.Labels).To(HaveKeyWithValue("team", "payments"))
+		Expect(deployment.Labels).To(HaveKeyWithValue("tier", "api"))
+		Expect(container.Env).To(ConsistOf(corev1.EnvVar{Name: "LOG_LEVEL", Value: "debug"}))
+		Expect(container.Resources.Limits.Cpu().String()).To(Equal("1"))
+		Expect(container.Resources.Limits.Memory().String()).To(Equal("256Mi"))
+		Expect(container.ReadinessProbe).NotTo(BeNil())
+		Expect(container.ReadinessProbe.HTTPGet.Path).To(Equal("/ready"))
+
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(syrax.Status.Template).NotTo(BeNil())
+		Expect(syrax.Status.Template.Name).To(Equal(templateName))
+		Expect(syrax.Status.Template.Generation).To(Equal(int64(1)))
+
+		By("keeping the merged values out of the stored spec")
+		Expect(syrax.Spec.DeploymentSpec.Image).To(Equal("shop/api:1.0"))
+		Expect(syrax.Spec.DeploymentSpec.Replicas).To(BeNil())
+		Expect(syrax.Spec.Labels).NotTo(HaveKey("team"))
+	})
+
+	It("should roll out changes of the template", func() {
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		template := &targaryenv2.SyraxTemplate{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: templateName}, template)).To(Succeed())
+		template.Spec.DeploymentSpec.Replicas = ptr.To[int32](5)
+		template.Spec.DeploymentSpec.Resources.Limits[corev1.ResourceMemory] = resource.MustParse("512Mi")
+		Expect(k8sClient.Update(ctx, template)).To(Succeed())
+
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		deployment := ownedDeployment(ctx, typeNamespacedName)
+		Expect(*deployment.Spec.Replicas).To(Equal(int32(5)))
+		Expect(deployment.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().String()).To(Equal("512Mi"))
+
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(syrax.Status.Template.Generation).To(Equal(template.Generation))
+	})
+
+	It("should keep the template labels out of the selectors", func() {
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		deployment := ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Labels).To(HaveKeyWithValue("team", "payments"))
+		Expect(deployment.Spec.Selector.MatchLabels).NotTo(HaveKey("team"))
+		Expect(deployment.Spec.Selector.MatchLabels).To(HaveKeyWithValue("tier", "api"))
+
+		By("updating the pods when a template label changes")
+		template := &targaryenv2.SyraxTemplate{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: templateName}, template)).To(Succeed())
+		template.Spec.Labels["team"] = "checkout"
+		Expect(k8sClient.Update(ctx, template)).To(Succeed())
+
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		deployment = ownedDeployment(ctx, typeNamespacedName)
+		Expect(deployment.Spec.Template.Labels).To(HaveKeyWithValue("team", "checkout"))
+		Expect(deployment.Spec.Selector.MatchLabels).NotTo(HaveKey("team"))
+	})
+
+	It("should not wait for a missing template to clean up a deleted syrax", func() {
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		syrax.Spec.DeletionPolicy = targaryenv2.DeletionPolicyDelete
+		Expect(k8sClient.Update(ctx, syrax)).To(Succeed())
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		template := &targaryenv2.SyraxTemplate{ObjectMeta: metav1.ObjectMeta{Name: templateName}}
+		Expect(k8sClient.Delete(ctx, template)).To(Succeed())
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, syrax)).To(Succeed())
+
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+		Expect(apierrors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, syrax))).To(BeTrue())
+	})
+
+	It("should report a missing template", func() {
+		template := &targaryenv2.SyraxTemplate{ObjectMeta: metav1.ObjectMeta{Name: templateName}}
+		Expect(k8sClient.Delete(ctx, template)).To(Succeed())
+
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).To(HaveOccurred())
+
+		syrax := &targaryenv2.Syrax{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, syrax)).To(Succeed())
+		condition := meta.FindStatusCondition(syrax.Status.Conditions, targaryenv2.ConditionDegraded)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal(string(errorMissingReference)))
+	})
+
+	It("should leave the images that name a registry alone", func() {
+		withRegistry := func(image, registry string) string {
+			template := &targaryenv2.SyraxTemplate{Spec: targaryenv2.SyraxTemplateSpec{ImageRegistry: registry}}
+			syrax := &targaryenv2.Syrax{Spec: targaryenv2.SyraxSpec{DeploymentSpec: targaryenv2.DeploymentSpec{Image: image}}}
+			Expect(template.Apply(syrax)).To(Succeed())
+			return syrax.Spec.DeploymentSpec.Image
+		}
+		Expect(withRegistry("nginx:1.25", "registry.example.com")).To(Equal("registry.example.com/nginx:1.25"))
+		Expect(withRegistry("quay.io/team/app:1.0", "registry.example.com")).To(Equal("quay.io/team/app:1.0"))
+		Expect(withRegistry("localhost/app", "registry.example.com")).To(Equal("localhost/app"))
+		Expect(withRegistry("registry:5000/app", "registry.example.com")).To(Equal("registry:5000/app"))
+		Expect(withRegistry("nginx", "")).To(Equal("nginx"))
+	})
+})
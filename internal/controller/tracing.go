@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	namespcedname "k8s.io/apimachinery/pkg/types"
+	syraxv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// tracerName identifies the spans of the syrax controller.
+const tracerName = "resource.controller.sigs/resource-controller-k8s-sigs/internal/controller"
+
+// tracer returns the tracer of the reconciler, or the one of the global
+// provider, which does not record anything unless a provider is installed.
+func (r *SyraxReconciler) tracer() trace.Tracer {
+	if r.Tracer != nil {
+		return r.Tracer
+	}
+	return otel.Tracer(tracerName)
+}
+
+// startSpan starts a span named after an operation of the reconciler.
+func (r *SyraxReconciler) startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return r.tracer().Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// syraxAttributes returns the attributes identifying a syrax on a span.
+func syraxAttributes(key namespcedname.NamespacedName) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("syrax.namespace", key.Namespace),
+		attribute.String("syrax.name", key.Name),
+	}
+}
+
+// setSyraxGeneration adds the generation of the syrax to the current span.
+func setSyraxGeneration(ctx context.Context, syrax *syraxv2.Syrax) {
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("syrax.generation", syrax.Generation))
+}
+
+// startChildSpan starts a span for an API call on a child object.
+func (r *SyraxReconciler) startChildSpan(ctx context.Context, operation string, obj client.Object, name string) (context.Context, trace.Span) {
+	kind := r.childKind(obj)
+	return r.startSpan(ctx, operation+kind, attribute.String("child.kind", kind), attribute.String("child.name", name))
+}
+
+// getChild gets a child object within its own span.
+func (r *SyraxReconciler) getChild(ctx context.Context, key namespcedname.NamespacedName, obj client.Object) error {
+	ctx, span := r.startChildSpan(ctx, "Get", obj, key.Name)
+	err := r.Get(ctx, key, obj)
+	endSpan(span, client.IgnoreNotFound(err))
+	return err
+}
+
+// endSpan records the outcome of the operation and ends the span.
+func endSpan(span trace.Span, err error) {
+	span.SetAttributes(attribute.String("outcome", outcome(err)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("Syrax tracing", func() {
+	const resourceName = "traced-resource"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+
+	BeforeEach(func() {
+		resource := newSyrax(typeNamespacedName)
+		resource.Spec.DeploymentSpec.Replicas = ptr.To[int32](1)
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+	})
+
+	It("should record a span per reconcile with child spans for the API calls", func() {
+		exporter := tracetest.NewInMemoryExporter()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+		controllerReconciler := newReconciler()
+		controllerReconciler.Tracer = provider.Tracer(tracerName)
+		reconcileSyrax(ctx, controllerReconciler, typeNamespacedName)
+
+		spans := map[string]tracetest.SpanStub{}
+		for _, span := range exporter.GetSpans() {
+			spans[span.Name] = span
+		}
+		Expect(spans).To(HaveKey("Reconcile"))
+		for _, name := range []string{"ResolveNames", "GetDeployment", "CreateDeployment", "GetService", "CreateService", "UpdateStatus"} {
+			Expect(spans).To(HaveKey(name))
+			Expect(spans[name].Parent.SpanID()).To(Equal(spans["Reconcile"].SpanContext.SpanID()))
+		}
+		Expect(spans["Reconcile"].Attributes).To(ContainElements(
+			attribute.String("syrax.namespace", "default"),
+			attribute.String("syrax.name", resourceName),
+			attribute.String("outcome", outcomeSuccess),
+		))
+		Expect(spans["CreateDeployment"].Attributes).To(ContainElement(attribute.String("child.kind", "Deployment")))
+	})
+})
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	targaryenv1 "resource.controller.sigs/resource-controller-k8s-sigs/api/v1"
+	targaryenv2 "resource.controller.sigs/resource-controller-k8s-sigs/api/v2"
+)
+
+var _ = Describe("Syrax validation", func() {
+	const resourceName = "validation-resource"
+
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+
+	namedSyrax := func() *targaryenv2.Syrax {
+		syrax := newSyrax(typeNamespacedName)
+		syrax.Spec.DeploymentSpec.Name = "web"
+		syrax.Spec.ServiceSpec.Name = "web"
+		return syrax
+	}
+
+	expectInvalid := func(err error, message string) {
+		Expect(err).To(HaveOccurred())
+		Expect(apierrors.IsInvalid(err)).To(BeTrue(), err.Error())
+		Expect(err.Error()).To(ContainSubstring(message))
+	}
+
+	AfterEach(func() {
+		deleteSyrax(ctx, typeNamespacedName)
+	})
+
+	It("should reject a nodePort on a ClusterIP service", func() {
+		syrax := namedSyrax()
+		syrax.Spec.ServiceSpec.ServiceType = corev1.ServiceTypeClusterIP
+		syrax.Spec.ServiceSpec.Ports[0].NodePort = ptr.To[int32](30080)
+		expectInvalid(k8sClient.Create(ctx, syrax), "nodePort can only be set for NodePort and LoadBalancer services")
+
+		syrax.Spec.ServiceSpec.ServiceType = corev1.ServiceTypeLoadBalancer
+		Expect(k8sClient.Create(ctx, syrax)).To(Succeed())
+	})
+
+	It("should reject a nodePort on a ClusterIP service in v1", func() {
+		syrax := &targaryenv1.Syrax{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      resourceName,
+				Namespace: "default",
+			},
+			Spec: targaryenv1.SyraxSpec{
+				DeploymentSpec: targaryenv1.DeploymentSpec{Image: "nginx:1.25"},
+				ServiceSpec: targaryenv1.ServiceSpec{
+					ServiceType: corev1.ServiceTypeClusterIP,
+					Port:        ptr.To[int32](80),
+					NodePort:    ptr.To[int32](30080),
+				},
+			},
+		}
+		expectInvalid(k8sClient.Create(ctx, syrax), "NodePort can only be set for NodePort and LoadBalancer services")
+	})
+
+	It("should reject ports out of range", func() {
+		syrax := namedSyrax()
+		syrax.Spec.ServiceSpec.Ports[0].Port = 0
+		expectInvalid(k8sClient.Create(ctx, syrax), "spec.serviceSpec.ports[0].port")
+
+		syrax = namedSyrax()
+		syrax.Spec.ServiceSpec.Ports[0].TargetPort = ptr.To[int32](70000)
+		expectInvalid(k8sClient.Create(ctx, syrax), "spec.serviceSpec.ports[0].targetPort")
+	})
+})
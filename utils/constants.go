@@ -4,9 +4,46 @@ const ContainerName = "ros"
 const DefaultServiceType = "NodePort"
 const DefautReplicaCount = 2
 const DefaultDeletionPolicy = "WipeOut"
+const DefaultConfigMountPath = "/etc/config"
 
 var DefaultLabel map[string]string = map[string]string{
 	"dracarys": "im-now-the-servant-of-the-white-walkers",
 }
 var DefaultFinalizer string = "Hodor"
 var Kind = "Syrax"
+
+// LastGoodTemplateAnnotation holds the pod template of the last completed rollout of a deployment.
+const LastGoodTemplateAnnotation = "targaryen.resource.controller.sigs/last-good-template"
+
+// TrackLabel separates the pods of secondary deployments from the primary ones.
+const TrackLabel = "targaryen.resource.controller.sigs/track"
+const TrackCanary = "canary"
+const TrackPreview = "preview"
+
+// ComponentLabel holds the name of the children of a component of a Syrax, and
+// selects the pods of the component.
+const ComponentLabel = "targaryen.resource.controller.sigs/component"
+
+// ColorLabel tells the pods of the two blue-green deployments apart.
+const ColorLabel = "targaryen.resource.controller.sigs/color"
+const ColorBlue = "blue"
+const ColorGreen = "green"
+
+// PromoteAnnotation approves the promotion of a blue-green preview when set to "true" on a Syrax.
+const PromoteAnnotation = "targaryen.resource.controller.sigs/promote"
+
+// PausedAnnotation and PausedAnnotationAlias pause the reconciliation of a
+// Syrax when set to "true".
+const PausedAnnotation = "syrax.targaryen/paused"
+const PausedAnnotationAlias = "targaryen.resource.controller.sigs/paused"
+
+// SyraxSetLabel holds the name of the SyraxSet a Syrax was generated by.
+const SyraxSetLabel = "targaryen.resource.controller.sigs/syraxset"
+
+// ConfigHashAnnotation holds the hash of the config files of a Syrax on the pod
+// template, so that changing them rolls the pods.
+const ConfigHashAnnotation = "targaryen.resource.controller.sigs/config-hash"
+
+// RestartedAtAnnotation holds the restartAt of a Syrax on the pod template, so
+// that changing it restarts the pods.
+const RestartedAtAnnotation = "targaryen.resource.controller.sigs/restartedAt"